@@ -1,16 +1,21 @@
 package main
 
 import (
-	"analyzer/datasource"
+	"analyzer/config"
 	"analyzer/models"
-	"database/sql"
+	"analyzer/sv"
+	"context"
 	"encoding/csv"
+	"errors"
+	"flag"
 	"fmt"
-	"github.com/Masterminds/semver/v3"
 	"github.com/cheggaaa/pb/v3"
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
@@ -20,16 +25,35 @@ type InputRecord struct {
 }
 
 func main() {
-	if err := handler(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := handler(ctx); err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Println("中断しました")
+			stop()
+			os.Exit(1)
+		}
 		panic(err)
 	}
 }
 
-const (
-	ecosystemType = "npm"
-)
+func handler(ctx context.Context) error {
+	var ecosystemFlag string
+	flag.StringVar(&ecosystemFlag, "ecosystem", string(models.Npm), "依存関係を解析するエコシステム")
+	dbFlags := config.RegisterDatabaseFlags(flag.CommandLine)
+	flag.Parse()
+
+	ecosystemType := models.EcosystemType(ecosystemFlag)
+	if !ecosystemType.IsValid() {
+		return fmt.Errorf("got unknown ecosystem type. ecosystem: '%s'", ecosystemType)
+	}
+
+	dbConfig, err := dbFlags.Load()
+	if err != nil {
+		return err
+	}
 
-func handler() error {
 	file, err := os.Open("inputs.tsv")
 	if err != nil {
 		return err
@@ -74,7 +98,7 @@ func handler() error {
 	})
 
 	log.Println("record count:", len(records))
-	db, err := sql.Open("mysql", "root@(localhost:3306)/lib")
+	repository, err := dbConfig.OpenRepository(ctx)
 	if err != nil {
 		return err
 	}
@@ -83,9 +107,14 @@ func handler() error {
 
 	count := 1
 	for _, record := range records {
+		// 中断したときは、それまでに書き出した辺を残して終わる
+		if err := ctx.Err(); err != nil {
+			bar.Finish()
+			return err
+		}
 		bar.Increment()
 
-		releases, err := datasource.FetchMergedTwoPackageReleasesWithSort(db, ecosystemType, record.ProjectId, record.DependencyProjectId)
+		releases, err := repository.FetchMergedTwoPackageReleasesWithSort(ctx, ecosystemType, record.ProjectId, record.DependencyProjectId)
 		if err != nil {
 			log.Println("some error raised.", err)
 			continue
@@ -105,7 +134,7 @@ func handler() error {
 				}
 
 				// 最新から順に遡って、利用可能なものを探す
-				usedDependencyPackageRelease, err := findLatestDependencyPackageVersion(releases[0:i], *release.DependencyRequirements)
+				usedDependencyPackageRelease, err := findLatestDependencyPackageVersion(ecosystemType, releases[0:i], *release.DependencyRequirements)
 				if err != nil {
 					//log.Println(err)
 					continue
@@ -128,7 +157,7 @@ func handler() error {
 					continue
 				}
 
-				isSatisfy, err := isSatisfyDependencyRequirement(*releases[latestProjectReleaseIndex].DependencyRequirements, release)
+				isSatisfy, err := isSatisfyDependencyRequirement(ecosystemType, *releases[latestProjectReleaseIndex].DependencyRequirements, release)
 				if err != nil {
 					//log.Println(err)
 					continue
@@ -155,14 +184,14 @@ func handler() error {
 	return nil
 }
 
-func findLatestDependencyPackageVersion(releases []models.ReleaseLog, requirements string) (*models.ReleaseLog, error) {
+func findLatestDependencyPackageVersion(ecosystemType models.EcosystemType, releases []models.ReleaseLog, requirements string) (*models.ReleaseLog, error) {
 	for i := len(releases) - 1; i >= 0; i-- {
 		if releases[i].PackageType == "vul_package" {
-			v, err := semver.NewVersion(releases[i].VersionNumber)
+			v, err := sv.NewVersion(ecosystemType, releases[i].VersionNumber)
 			if err != nil {
 				return nil, err
 			}
-			c, err := semver.NewConstraint(requirements)
+			c, err := sv.NewConstraint(ecosystemType, requirements)
 			if err != nil {
 				return nil, err
 			}
@@ -174,12 +203,12 @@ func findLatestDependencyPackageVersion(releases []models.ReleaseLog, requiremen
 	return nil, fmt.Errorf("利用可能なバージョンが見つかりませんでした")
 }
 
-func isSatisfyDependencyRequirement(requirement string, release models.ReleaseLog) (bool, error) {
-	v, err := semver.NewVersion(release.VersionNumber)
+func isSatisfyDependencyRequirement(ecosystemType models.EcosystemType, requirement string, release models.ReleaseLog) (bool, error) {
+	v, err := sv.NewVersion(ecosystemType, release.VersionNumber)
 	if err != nil {
 		return false, err
 	}
-	c, err := semver.NewConstraint(requirement)
+	c, err := sv.NewConstraint(ecosystemType, requirement)
 	if err != nil {
 		return false, err
 	}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"analyzer/exposure"
+	"analyzer/models"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AffectedPackagesHeader は影響期間の出力CSVのヘッダー
+var AffectedPackagesHeader = []string{
+	"project_id",
+	"vul_project_id",
+	"vul_start_datetime",
+	"vul_end_datetime",
+	"vul_start_timestamp",
+	"vul_end_timestamp",
+	"compliantType",
+	"vul_start_dependency_compliant",
+	"vul_start_version",
+	"vul_deps",
+	// 脆弱性パッケージが(このパッケージも含めて)影響を与えたパッケージの総数
+	"vul_total_count",
+	"source_rank",
+	// 元の脆弱性パッケージからvul_project_idまでの依存経路
+	"vul_path",
+	// 解析時点で影響が続いていて、vul_end_datetimeが打ち切られているかどうか
+	"censored",
+	// 元の脆弱性が公開された日時
+	"disclosure_datetime",
+	"disclosure_timestamp",
+	// 公開前・公開後それぞれで影響を受けていた秒数. 公開後も影響が続いている場合は公開後の秒数は空
+	"pre_disclosure_seconds",
+	"post_disclosure_seconds",
+	// 公開から影響を受け終わるまでの秒数. 公開前に影響を受け終わっている場合は空
+	"disclosure_to_end_seconds",
+	// 影響を受けている間に初めて公開された修正版. 存在しなければ空
+	"fix_datetime",
+	"fix_timestamp",
+	"fix_version",
+	// 影響を受けていたときの依存関係制約が修正版を許容していたかどうか
+	"fix_admitted",
+	// no_fix, auto_picked, blocked_by_constraint, admitted_not_picked のいずれか
+	"fix_status",
+	// 修正版が存在しなかった秒数と、修正版が存在したが依存関係制約によって採用されなかった秒数
+	"no_fix_seconds",
+	"blocked_seconds",
+	// constraint_changed, upstream_fix, dependency_dropped, open のいずれか
+	"end_cause",
+	// 影響を受け終わる直前と直後の依存関係制約
+	"pre_end_dependency_requirement",
+	"end_dependency_requirement",
+	// 影響を受け始めたときの依存関係の種類. runtime, dev, build, peer, optional のいずれか
+	"dependency_kind",
+}
+
+// projectColumnValues は -project-columns で影響期間の出力CSVの末尾に追加できる、影響を受けたパッケージの projects テーブルの列と、リポジトリのスター数
+var projectColumnValues = map[string]func(p *models.Package) string{
+	"project_name":                     func(p *models.Package) string { return p.Name },
+	"created_timestamp":                func(p *models.Package) string { return p.CreatedTimestamp },
+	"homepage_url":                     func(p *models.Package) string { return p.HomepageUrl },
+	"licenses":                         func(p *models.Package) string { return p.Licenses },
+	"repository_url":                   func(p *models.Package) string { return p.RepositoryUrl },
+	"versions_count":                   func(p *models.Package) string { return strconv.FormatInt(p.VersionsCount, 10) },
+	"latest_release_publish_timestamp": func(p *models.Package) string { return p.LatestReleasePublishTimestamp },
+	"latest_release_number":            func(p *models.Package) string { return p.LatestReleaseNumber },
+	"dependent_projects_count":         func(p *models.Package) string { return strconv.FormatInt(p.DependentProjectsCount, 10) },
+	"language":                         func(p *models.Package) string { return p.Language },
+	"status":                           func(p *models.Package) string { return p.Status },
+	"dependent_repositories_count":     func(p *models.Package) string { return strconv.FormatInt(p.DependentRepositoriesCount, 10) },
+	"stars":                            func(p *models.Package) string { return strconv.FormatInt(p.Stars, 10) },
+}
+
+// ParseProjectColumns はカンマ区切りの -project-columns を検証して列名の一覧にする
+func ParseProjectColumns(s string) ([]string, error) {
+	columns := make([]string, 0)
+	for _, column := range strings.Split(s, ",") {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			continue
+		}
+		if _, ok := projectColumnValues[column]; !ok {
+			names := make([]string, 0, len(projectColumnValues))
+			for name := range projectColumnValues {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("got unknown project column. column: '%s', available: %s", column, strings.Join(names, ", "))
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// AffectedPackagesHeaderWith はAffectedPackagesHeaderの末尾にprojectColumnsを追加したヘッダーを返す
+func AffectedPackagesHeaderWith(projectColumns []string) []string {
+	header := make([]string, 0, len(AffectedPackagesHeader)+len(projectColumns))
+	header = append(header, AffectedPackagesHeader...)
+	return append(header, projectColumns...)
+}
+
+// AffectedPackageRecord は影響期間1つ分の出力CSVの行を作る. 末尾にはaffectedPackageのprojectColumnsの値を追加する
+func AffectedPackageRecord(vulPackage VulPackage, r exposure.Interval, totalCount int, affectedPackage *models.Package, projectColumns []string) []string {
+	// 解析時点を指定せずに解析した、影響が続いている期間は終わりが無いので、終了日時の列は空にする
+	vulEndDate, vulEndTimestamp := "", ""
+	if r.VulEndDate != nil {
+		vulEndDate = r.VulEndDate.String()
+		vulEndTimestamp = strconv.FormatInt(r.VulEndDate.Unix(), 10)
+	}
+	record := []string{
+		r.PackageId,
+		vulPackage.PackageId,
+		r.VulStartDate.String(),
+		vulEndDate,
+		strconv.FormatInt(r.VulStartDate.Unix(), 10),
+		vulEndTimestamp,
+		strconv.FormatInt(int64(r.CompliantType), 10),
+		r.VulStartDependencyRequirement,
+		r.VulStartVersion.String(),
+		strconv.FormatInt(vulPackage.Deps, 10),
+		strconv.FormatInt(int64(totalCount), 10),
+		strconv.FormatInt(affectedPackage.SourceRank, 10),
+		strings.Join(vulPackage.Path, ">"),
+		strconv.FormatBool(r.Censored),
+	}
+
+	if vulPackage.PublishedAt.IsZero() {
+		// 公開日時が分からない
+		record = append(record, "", "", "", "", "")
+	} else {
+		split := r.SplitAtDisclosure(vulPackage.PublishedAt)
+		record = append(record,
+			vulPackage.PublishedAt.String(),
+			strconv.FormatInt(vulPackage.PublishedAt.Unix(), 10),
+			formatSeconds(&split.PreDisclosureDuration),
+			formatSeconds(split.PostDisclosureDuration),
+			formatSeconds(split.DisclosureToEndDuration),
+		)
+	}
+
+	if r.FixReleaseDate == nil {
+		record = append(record, "", "", "")
+	} else {
+		record = append(record,
+			r.FixReleaseDate.String(),
+			strconv.FormatInt(r.FixReleaseDate.Unix(), 10),
+			r.FixVersion.String(),
+		)
+	}
+	noFixDuration, blockedDuration := r.SplitAtFix()
+	record = append(record,
+		strconv.FormatBool(r.FixAdmitted),
+		string(r.FixStatus),
+		formatSeconds(&noFixDuration),
+		formatSeconds(&blockedDuration),
+		string(r.EndCause),
+		r.PreEndDependencyRequirement,
+		r.EndDependencyRequirement,
+		string(r.DependencyKind),
+	)
+
+	for _, column := range projectColumns {
+		record = append(record, projectColumnValues[column](affectedPackage))
+	}
+	return record
+}
+
+func formatSeconds(d *time.Duration) string {
+	if d == nil {
+		return ""
+	}
+	return strconv.FormatInt(int64(d.Seconds()), 10)
+}
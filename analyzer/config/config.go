@@ -0,0 +1,294 @@
+package config
+
+import (
+	"analyzer/datasource"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 環境変数の接頭辞. 例: ANALYZER_DB_DSN
+const envPrefix = "ANALYZER_DB_"
+
+// Database は解析に使うデータベースへの接続設定.
+// 既定値、設定ファイル(JSON)、環境変数、コマンドラインのフラグの順に上書きする
+//
+//	{
+//	  "driver": "mysql",
+//	  "dsn": "root@(localhost:3306)/lib",
+//	  "max_open_conns": 10,
+//	  "max_idle_conns": 10,
+//	  "conn_max_lifetime": "5m",
+//	  "query_timeout": "10m",
+//	  "retry_attempts": 3,
+//	  "retry_backoff": "1s"
+//	}
+type Database struct {
+	// database/sqlのドライバ名. mysql, sqlite3, postgres のいずれか
+	Driver string
+	DSN    string
+	// コネクションプールの大きさ. 0なら無制限(MaxIdleConnsは database/sql の既定値).
+	// kafka_clientのproducerは依存しているパッケージを読みながらprojectsを問い合わせるので、2以上が必要
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// データベースへの問い合わせ1回にかけられる時間. 0なら無制限
+	QueryTimeout time.Duration
+	// 接続や問い合わせが一時的なエラーで失敗したときに試す回数(最初の1回を含む)と、再試行までの待ち時間(試すたびに倍にする)
+	RetryAttempts int
+	RetryBackoff  time.Duration
+}
+
+// DefaultDatabase は手元のMySQLに取り込んだLibraries.ioのデータセットにつなぐ既定の設定
+func DefaultDatabase() Database {
+	return Database{
+		Driver:          "mysql",
+		DSN:             "root@(localhost:3306)/lib",
+		MaxOpenConns:    10,
+		MaxIdleConns:    10,
+		ConnMaxLifetime: 5 * time.Minute,
+		RetryAttempts:   3,
+		RetryBackoff:    time.Second,
+	}
+}
+
+// databaseFile は設定ファイルの形式. 時間は "30s" のような time.ParseDuration の形式で書く
+type databaseFile struct {
+	Driver          *string `json:"driver"`
+	DSN             *string `json:"dsn"`
+	MaxOpenConns    *int    `json:"max_open_conns"`
+	MaxIdleConns    *int    `json:"max_idle_conns"`
+	ConnMaxLifetime *string `json:"conn_max_lifetime"`
+	QueryTimeout    *string `json:"query_timeout"`
+	RetryAttempts   *int    `json:"retry_attempts"`
+	RetryBackoff    *string `json:"retry_backoff"`
+}
+
+// DatabaseFlags はデータベースの接続設定のフラグ. Loadはフラグを解析した後に呼ぶ
+type DatabaseFlags struct {
+	fs   *flag.FlagSet
+	file *string
+}
+
+// 設定の項目. フラグ名は -db-<name>、環境変数名は ANALYZER_DB_<NAME>
+var databaseSettings = []struct {
+	name  string
+	usage string
+}{
+	{"driver", "database/sqlのドライバ名(mysql, sqlite3, postgres)"},
+	{"dsn", "データベースのDSN"},
+	{"max-open-conns", "同時に開く接続の最大数"},
+	{"max-idle-conns", "待機させておく接続の最大数"},
+	{"conn-max-lifetime", "接続を使い回す最大の時間(例: 5m)"},
+	{"query-timeout", "データベースへの問い合わせ1回にかけられる時間(例: 10m). 0なら無制限"},
+	{"retry-attempts", "接続や問い合わせが一時的なエラーで失敗したときに試す回数(最初の1回を含む)"},
+	{"retry-backoff", "最初の再試行までの待ち時間(例: 1s). 試すたびに倍にする"},
+}
+
+// RegisterDatabaseFlags はfsにデータベースの接続設定のフラグ(-db-config, -db-dsn など)を登録する
+func RegisterDatabaseFlags(fs *flag.FlagSet) *DatabaseFlags {
+	f := &DatabaseFlags{
+		fs:   fs,
+		file: fs.String("db-config", "", "データベースの接続設定のJSONファイル. 環境変数 "+envPrefix+"CONFIG でも指定できる"),
+	}
+	for _, s := range databaseSettings {
+		fs.String("db-"+s.name, "", s.usage)
+	}
+	return f
+}
+
+// Load は既定値に、設定ファイル、環境変数、明示的に指定されたフラグの順で上書きした設定を返す
+func (f *DatabaseFlags) Load() (Database, error) {
+	c := DefaultDatabase()
+
+	file := os.Getenv(envPrefix + "CONFIG")
+	if *f.file != "" {
+		file = *f.file
+	}
+	if file != "" {
+		if err := c.loadFile(file); err != nil {
+			return Database{}, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	for _, s := range databaseSettings {
+		env := envPrefix + envName(s.name)
+		if value, ok := os.LookupEnv(env); ok {
+			if err := c.set(s.name, value); err != nil {
+				return Database{}, fmt.Errorf("%s: %w", env, err)
+			}
+		}
+	}
+
+	var err error
+	f.fs.Visit(func(fl *flag.Flag) {
+		name := strings.TrimPrefix(fl.Name, "db-")
+		if !strings.HasPrefix(fl.Name, "db-") || name == "config" || err != nil {
+			return
+		}
+		if setErr := c.set(name, fl.Value.String()); setErr != nil {
+			err = fmt.Errorf("-%s: %w", fl.Name, setErr)
+		}
+	})
+	if err != nil {
+		return Database{}, err
+	}
+
+	return c, c.validate()
+}
+
+func (c *Database) loadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file databaseFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return err
+	}
+
+	values := map[string]*string{
+		"driver":            file.Driver,
+		"dsn":               file.DSN,
+		"conn-max-lifetime": file.ConnMaxLifetime,
+		"query-timeout":     file.QueryTimeout,
+		"retry-backoff":     file.RetryBackoff,
+	}
+	for name, value := range values {
+		if value == nil {
+			continue
+		}
+		if err := c.set(name, *value); err != nil {
+			return err
+		}
+	}
+	if file.MaxOpenConns != nil {
+		c.MaxOpenConns = *file.MaxOpenConns
+	}
+	if file.MaxIdleConns != nil {
+		c.MaxIdleConns = *file.MaxIdleConns
+	}
+	if file.RetryAttempts != nil {
+		c.RetryAttempts = *file.RetryAttempts
+	}
+	return nil
+}
+
+// set は設定の項目nameを文字列valueで上書きする
+func (c *Database) set(name string, value string) error {
+	var err error
+	switch name {
+	case "driver":
+		c.Driver = value
+	case "dsn":
+		c.DSN = value
+	case "max-open-conns":
+		c.MaxOpenConns, err = strconv.Atoi(value)
+	case "max-idle-conns":
+		c.MaxIdleConns, err = strconv.Atoi(value)
+	case "conn-max-lifetime":
+		c.ConnMaxLifetime, err = time.ParseDuration(value)
+	case "query-timeout":
+		c.QueryTimeout, err = time.ParseDuration(value)
+	case "retry-attempts":
+		c.RetryAttempts, err = strconv.Atoi(value)
+	case "retry-backoff":
+		c.RetryBackoff, err = time.ParseDuration(value)
+	default:
+		return fmt.Errorf("got unknown database setting. name: '%s'", name)
+	}
+	return err
+}
+
+func (c Database) validate() error {
+	if !c.Dialect().IsValid() {
+		return fmt.Errorf("got unknown database driver. driver: '%s'", c.Driver)
+	}
+	if c.DSN == "" {
+		return fmt.Errorf("データベースのDSNが指定されていません")
+	}
+	if c.RetryAttempts < 1 {
+		return fmt.Errorf("retry_attemptsは1以上を指定してください. retry_attempts: %d", c.RetryAttempts)
+	}
+	return nil
+}
+
+// Dialect はドライバに対応するSQLの方言
+func (c Database) Dialect() datasource.Dialect {
+	return datasource.Dialect(c.Driver)
+}
+
+// Open はデータベースを開き、コネクションプールを設定してから接続できるか確かめる.
+// ドライバは呼び出し側でimportしておく必要がある. ctxがキャンセルされたら再試行をやめる
+func (c Database) Open(ctx context.Context) (*sql.DB, error) {
+	db, err := sql.Open(c.Driver, c.DSN)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+
+	backoff := c.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err = c.ping(ctx, db)
+		if err == nil {
+			return db, nil
+		}
+		if attempt >= c.RetryAttempts || ctx.Err() != nil {
+			break
+		}
+		log.Printf("データベースに接続できませんでした. %s後に再試行します. attempt: %d/%d, error: %s", backoff, attempt, c.RetryAttempts, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	if closeErr := db.Close(); closeErr != nil {
+		log.Printf("エラーが発生しました. error: %s", closeErr)
+	}
+	return nil, fmt.Errorf("データベースに接続できませんでした. attempts: %d: %w", c.RetryAttempts, err)
+}
+
+func (c Database) ping(ctx context.Context, db *sql.DB) error {
+	if c.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.QueryTimeout)
+		defer cancel()
+	}
+	return db.PingContext(ctx)
+}
+
+// OpenRepository はデータベースを開き、そこからデータを取得するSQLRepositoryを作る.
+// 問い合わせにもQueryTimeoutとRetryAttempts, RetryBackoffを使う
+func (c Database) OpenRepository(ctx context.Context) (*datasource.SQLRepository, error) {
+	db, err := c.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return datasource.NewSQLRepositoryWithOptions(db, c.SQLOptions()), nil
+}
+
+// SQLOptions はSQLRepositoryの問い合わせの設定
+func (c Database) SQLOptions() datasource.SQLOptions {
+	return datasource.SQLOptions{
+		Dialect:       c.Dialect(),
+		QueryTimeout:  c.QueryTimeout,
+		RetryAttempts: c.RetryAttempts,
+		RetryBackoff:  c.RetryBackoff,
+	}
+}
+
+// envName はフラグ名の一部(max-open-conns)を環境変数名の一部(MAX_OPEN_CONNS)にする
+func envName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
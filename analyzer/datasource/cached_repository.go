@@ -0,0 +1,179 @@
+package datasource
+
+import (
+	"analyzer/models"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CachedRepository は脆弱性パッケージごとのリリース履歴(依存しているパッケージと、脆弱性パッケージ自身)を
+// ディスクにキャッシュする. 解析のロジックを変えて実行し直すときに、同じクエリをデータベースに投げないようにする.
+// それ以外のメソッドはそのままrepositoryに問い合わせる
+//
+// キャッシュは {dir}/{snapshot}/{ecosystem}/{vulPackageId}.*.json.gz に置く.
+// snapshotはデータセットの版(Libraries.ioのダンプの日付など)で、データセットを入れ替えたら変える
+type CachedRepository struct {
+	Repository
+	dir string
+}
+
+// 依存しているパッケージ1つ分のキャッシュの行
+type cachedAffectedPackage struct {
+	ProjectId   string              `json:"project_id"`
+	ReleaseLogs []models.ReleaseLog `json:"release_logs"`
+}
+
+func NewCachedRepository(repository Repository, dir string, snapshot string) (*CachedRepository, error) {
+	if snapshot == "" || strings.ContainsAny(snapshot, `/\`) || snapshot == "." || snapshot == ".." {
+		return nil, fmt.Errorf("キャッシュのsnapshotが不正です. snapshot: '%s'", snapshot)
+	}
+	return &CachedRepository{
+		Repository: repository,
+		dir:        filepath.Join(dir, snapshot),
+	}, nil
+}
+
+// Invalidate はこのsnapshotのキャッシュを全て消す
+func (r *CachedRepository) Invalidate() error {
+	return os.RemoveAll(r.dir)
+}
+
+func (r *CachedRepository) FetchAffectedPackagesWithVersions(ctx context.Context, ecosystem models.EcosystemType, vulPackageId string, fn AffectedPackageFunc) error {
+	path, err := r.path(ecosystem, vulPackageId, "affected")
+	if err != nil {
+		return err
+	}
+
+	if file, err := os.Open(path); err == nil {
+		defer func(file *os.File) {
+			err := file.Close()
+			if err != nil {
+				panic(err)
+			}
+		}(file)
+		return readAffectedPackages(ctx, file, fn)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// 読みながらfnに渡しつつ一時ファイルに書き、最後まで読めたときだけキャッシュにする
+	return writeCache(path, func(w *bufio.Writer) error {
+		encoder := json.NewEncoder(w)
+		return r.Repository.FetchAffectedPackagesWithVersions(ctx, ecosystem, vulPackageId, func(affectedPackageId string, releaseLogs []models.ReleaseLog) error {
+			if err := encoder.Encode(cachedAffectedPackage{ProjectId: affectedPackageId, ReleaseLogs: releaseLogs}); err != nil {
+				return err
+			}
+			return fn(affectedPackageId, releaseLogs)
+		})
+	})
+}
+
+func (r *CachedRepository) GetVulPackageVersionsById(ctx context.Context, vulPackageId string, ecosystemType models.EcosystemType) ([]models.ReleaseLog, error) {
+	path, err := r.path(ecosystemType, vulPackageId, "versions")
+	if err != nil {
+		return nil, err
+	}
+
+	var releaseLogs []models.ReleaseLog
+	if file, err := os.Open(path); err == nil {
+		defer func(file *os.File) {
+			err := file.Close()
+			if err != nil {
+				panic(err)
+			}
+		}(file)
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("キャッシュを読み込めません. path: %s: %w", path, err)
+		}
+		if err := json.NewDecoder(gz).Decode(&releaseLogs); err != nil {
+			return nil, fmt.Errorf("キャッシュを読み込めません. path: %s: %w", path, err)
+		}
+		return releaseLogs, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	releaseLogs, err = r.Repository.GetVulPackageVersionsById(ctx, vulPackageId, ecosystemType)
+	if err != nil {
+		return nil, err
+	}
+	err = writeCache(path, func(w *bufio.Writer) error {
+		return json.NewEncoder(w).Encode(releaseLogs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return releaseLogs, nil
+}
+
+func (r *CachedRepository) path(ecosystem models.EcosystemType, vulPackageId string, kind string) (string, error) {
+	if !ecosystem.IsValid() {
+		return "", fmt.Errorf("got unknown ecosystem type. ecosystem: '%s'", ecosystem)
+	}
+	if vulPackageId == "" || strings.ContainsAny(vulPackageId, `/\.`) {
+		return "", fmt.Errorf("キャッシュに使えないproject_idです. project_id: '%s'", vulPackageId)
+	}
+	return filepath.Join(r.dir, string(ecosystem), fmt.Sprintf("%s.%s.json.gz", vulPackageId, kind)), nil
+}
+
+func readAffectedPackages(ctx context.Context, file *os.File, fn AffectedPackageFunc) error {
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("キャッシュを読み込めません. path: %s: %w", file.Name(), err)
+	}
+	decoder := json.NewDecoder(gz)
+	for decoder.More() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var p cachedAffectedPackage
+		if err := decoder.Decode(&p); err != nil {
+			return fmt.Errorf("キャッシュを読み込めません. path: %s: %w", file.Name(), err)
+		}
+		if err := fn(p.ProjectId, p.ReleaseLogs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCache はwriteで書いた内容をgzipで圧縮してpathに置く. 途中で失敗したら何も残さない
+func writeCache(path string, write func(w *bufio.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func(name string) {
+		// リネームした後は消すものが無い
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			panic(err)
+		}
+	}(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	w := bufio.NewWriter(gz)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
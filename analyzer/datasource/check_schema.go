@@ -0,0 +1,157 @@
+package datasource
+
+import (
+	"analyzer/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+const (
+	existingTablesMySQLSql = `
+SELECT table_name
+FROM information_schema.tables
+WHERE table_schema=DATABASE()
+`
+	existingTablesSQLiteSql = `
+SELECT name
+FROM sqlite_master
+WHERE type='table'
+`
+	existingTablesPostgreSQLSql = `
+SELECT table_name
+FROM information_schema.tables
+WHERE table_schema=current_schema()
+`
+	existingIndexesMySQLSql = `
+SELECT DISTINCT index_name
+FROM information_schema.statistics
+WHERE table_schema=DATABASE() AND table_name=?
+`
+	existingIndexesSQLiteSql = `
+SELECT name
+FROM sqlite_master
+WHERE type='index' AND tbl_name=?
+`
+	existingIndexesPostgreSQLSql = `
+SELECT indexname
+FROM pg_indexes
+WHERE schemaname=current_schema() AND tablename=$1
+`
+)
+
+// SchemaNotReadyError は解析に必要なテーブル・インデックス・データが揃っていないことを表す
+type SchemaNotReadyError struct {
+	MissingTables  []string
+	MissingIndexes []string
+	EmptyTables    []string
+}
+
+func (e *SchemaNotReadyError) Error() string {
+	problems := make([]string, 0, 3)
+	if len(e.MissingTables) != 0 {
+		problems = append(problems, "テーブルがありません: "+strings.Join(e.MissingTables, ", "))
+	}
+	if len(e.MissingIndexes) != 0 {
+		problems = append(problems, "インデックスがありません: "+strings.Join(e.MissingIndexes, ", "))
+	}
+	if len(e.EmptyTables) != 0 {
+		problems = append(problems, "データが読み込まれていません: "+strings.Join(e.EmptyTables, ", "))
+	}
+	return fmt.Sprintf("スキーマの準備ができていません. %s", strings.Join(problems, ". "))
+}
+
+// CheckSchema はecosystemsの解析に必要なテーブルとインデックスがあり、データが読み込まれているかを確認する.
+// Optional なテーブルはデータが無くてもよい.
+// 揃っていなければ *SchemaNotReadyError を返す
+func CheckSchema(ctx context.Context, db *sql.DB, dialect Dialect, ecosystems []models.EcosystemType) error {
+	tables, err := existingTables(ctx, db, dialect)
+	if err != nil {
+		return err
+	}
+
+	notReady := &SchemaNotReadyError{}
+	for _, table := range SchemaTables(ecosystems) {
+		if !tables[table.Name] {
+			notReady.MissingTables = append(notReady.MissingTables, table.Name)
+			continue
+		}
+
+		indexes, err := existingIndexes(ctx, db, dialect, table.Name)
+		if err != nil {
+			return err
+		}
+		for _, name := range table.indexNames() {
+			if !indexes[name] {
+				notReady.MissingIndexes = append(notReady.MissingIndexes, name)
+			}
+		}
+
+		if table.Optional {
+			continue
+		}
+		var exists int
+		err = db.QueryRowContext(ctx, fmt.Sprintf("SELECT 1 FROM %s LIMIT 1", table.Name)).Scan(&exists)
+		if err == sql.ErrNoRows {
+			notReady.EmptyTables = append(notReady.EmptyTables, table.Name)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if len(notReady.MissingTables) != 0 || len(notReady.MissingIndexes) != 0 || len(notReady.EmptyTables) != 0 {
+		return notReady
+	}
+	return nil
+}
+
+// CheckSchema はecosystemsの解析に必要なスキーマが揃っているかを確認する
+func (r *SQLRepository) CheckSchema(ctx context.Context, ecosystems ...models.EcosystemType) error {
+	return CheckSchema(ctx, r.db, r.options.Dialect, ecosystems)
+}
+
+func existingTables(ctx context.Context, db *sql.DB, dialect Dialect) (map[string]bool, error) {
+	query := existingTablesMySQLSql
+	switch dialect {
+	case SQLite:
+		query = existingTablesSQLiteSql
+	case PostgreSQL:
+		query = existingTablesPostgreSQLSql
+	}
+	return queryNames(ctx, db, query)
+}
+
+func existingIndexes(ctx context.Context, db *sql.DB, dialect Dialect, table string) (map[string]bool, error) {
+	query := existingIndexesMySQLSql
+	switch dialect {
+	case SQLite:
+		query = existingIndexesSQLiteSql
+	case PostgreSQL:
+		query = existingIndexesPostgreSQLSql
+	}
+	return queryNames(ctx, db, query, table)
+}
+
+func queryNames(ctx context.Context, db *sql.DB, query string, args ...interface{}) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(rows)
+
+	names := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names[name] = true
+	}
+	return names, rows.Err()
+}
@@ -0,0 +1,76 @@
+package datasource
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect はデータベースの種類. database/sqlのドライバ名と同じ
+type Dialect string
+
+const (
+	MySQL      Dialect = "mysql"
+	SQLite     Dialect = "sqlite3"
+	PostgreSQL Dialect = "postgres"
+)
+
+// Dialects は対応しているデータベースの種類
+var Dialects = []Dialect{MySQL, SQLite, PostgreSQL}
+
+func (d Dialect) IsValid() bool {
+	for _, dialect := range Dialects {
+		if d == dialect {
+			return true
+		}
+	}
+	return false
+}
+
+// rebind はクエリのプレースホルダ ? を、データベースの書き方にする. PostgreSQLでは $1, $2, ... にする.
+// クエリの文字列リテラルには ? を含めないこと
+func (d Dialect) rebind(query string) string {
+	if d != PostgreSQL {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + strings.Count(query, "?"))
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteByte('$')
+		b.WriteString(strconv.Itoa(n))
+	}
+	return b.String()
+}
+
+// timestampFormat はリリース履歴などで日時を表す文字列の形式. MySQLのDATETIME型を文字列で読んだときと同じ
+const timestampFormat = "2006-01-02 15:04:05"
+
+// timestamp は日時の列を、どのデータベースからでも timestampFormat の文字列として読む.
+// MySQLとSQLiteは文字列のまま、PostgreSQLのTIMESTAMP型はtime.Timeとして返ってくる. NULLは空文字列にする
+type timestamp struct {
+	s *string
+}
+
+func (t timestamp) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*t.s = ""
+	case string:
+		*t.s = v
+	case []byte:
+		*t.s = string(v)
+	case time.Time:
+		*t.s = v.UTC().Format(timestampFormat)
+	default:
+		return fmt.Errorf("日時として読めない値です. value: %v (%T)", src, src)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package datasource
 
 import (
 	"analyzer/models"
+	"context"
 	"database/sql"
 )
 
@@ -9,45 +10,52 @@ type AffectedPackagesFromVulPackage struct {
 	ProjectId string `json:"project_id"`
 }
 
-const fetchAffectedPackagesFromVulPackageSqlTemplate = `
+const fetchAffectedPackagesFromVulPackageSql = `
 SELECT DISTINCT d.project_id
-FROM dependencies_{{.ecosystemType}} d
-WHERE d.dependency_project_id={{.vulPackageId}}
+FROM {dependencies} d
+WHERE d.dependency_project_id=?
 `
 
-func FetchAffectedPackagesFromVulPackage(db *sql.DB, ecosystem models.EcosystemType, vulPackageId string) ([]AffectedPackagesFromVulPackage, error) {
-	sqlString, err := buildStringWithParamsFromTemplate(fetchAffectedPackagesFromVulPackageSqlTemplate, map[string]string{
-		"vulPackageId":  vulPackageId,
-		"ecosystemType": string(ecosystem),
-	})
+func (r *SQLRepository) FetchAffectedPackagesFromVulPackage(ctx context.Context, ecosystem models.EcosystemType, vulPackageId string) ([]AffectedPackagesFromVulPackage, error) {
+	query, err := buildQuery(fetchAffectedPackagesFromVulPackageSql, ecosystem)
 	if err != nil {
 		return nil, err
 	}
-
-	rows, err := db.Query(sqlString)
+	stmt, err := r.prepare(ctx, query)
 	if err != nil {
 		return nil, err
 	}
-	defer func(rows *sql.Rows) {
-		err := rows.Close()
-		if err != nil {
-			panic(err)
-		}
-	}(rows)
-
-	packages := make([]AffectedPackagesFromVulPackage, 0)
-	for rows.Next() {
-		var p AffectedPackagesFromVulPackage
 
-		err := rows.Scan(
-			&p.ProjectId,
-		)
+	var packages []AffectedPackagesFromVulPackage
+	err = r.query(ctx, func(ctx context.Context) error {
+		rows, err := stmt.QueryContext(ctx, vulPackageId)
 		if err != nil {
-			return nil, err
+			return err
 		}
-
-		packages = append(packages, p)
+		defer func(rows *sql.Rows) {
+			err := rows.Close()
+			if err != nil {
+				panic(err)
+			}
+		}(rows)
+
+		packages = make([]AffectedPackagesFromVulPackage, 0)
+		for rows.Next() {
+			var p AffectedPackagesFromVulPackage
+
+			err := rows.Scan(
+				&p.ProjectId,
+			)
+			if err != nil {
+				return err
+			}
+
+			packages = append(packages, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
-
 	return packages, nil
 }
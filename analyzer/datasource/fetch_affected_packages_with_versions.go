@@ -2,69 +2,114 @@ package datasource
 
 import (
 	"analyzer/models"
+	"context"
 	"database/sql"
 )
 
-const fetchAffectedPackagesWithVersionsSqlTemplate = `
-SELECT d.project_id,d.project_name,d.version_id,v.number AS version_number,d.dependency_requirements,
-	   v.published_timestamp
-FROM dependencies_{{.ecosystemType}} d
-INNER JOIN versions_{{.ecosystemType}} v ON d.version_id=v.id
-WHERE d.dependency_project_id={{.vulPackageId}}
-ORDER BY d.project_id ASC, v.published_timestamp ASC
+// 依存元パッケージの全てのリリースを取得する. 脆弱性パッケージに依存していないリリースは 'not_depending' になる.
+// 1つのリリースが脆弱性パッケージに複数の種類(runtimeとdevなど)で依存している場合は、種類ごとに続けて並ぶ
+const fetchAffectedPackagesWithVersionsSql = `
+SELECT v.project_id,v.project_name,v.id AS version_id,v.number AS version_number,d.dependency_requirements,
+	   v.published_timestamp,
+	   CASE WHEN d.id IS NULL THEN 'not_depending' ELSE 'package' END AS type,
+	   COALESCE(d.dependency_kind, '') AS dependency_kind, COALESCE(d.optional_dependency, '') AS optional_dependency
+FROM {versions} v
+LEFT JOIN {dependencies} d ON d.version_id=v.id AND d.dependency_project_id=?
+WHERE v.project_id IN (
+	SELECT DISTINCT dd.project_id
+	FROM {dependencies} dd
+	WHERE dd.dependency_project_id=?
+)
+ORDER BY v.project_id ASC, v.published_timestamp ASC, v.id ASC
 `
 
-func FetchAffectedPackagesWithVersions(db *sql.DB, ecosystem models.EcosystemType, vulPackageId string) (map[string][]models.ReleaseLog, error) {
-	sqlString, err := buildStringWithParamsFromTemplate(fetchAffectedPackagesWithVersionsSqlTemplate, map[string]string{
-		"vulPackageId":  vulPackageId,
-		"ecosystemType": string(ecosystem),
-	})
+// 結果を1行ずつ読みながらfnに渡すので、QueryTimeoutはクエリ全体ではなく、次の行が届くまでの時間に適用する(fnの処理時間は含めない).
+// 一時的なエラーで再試行したときは、既にfnに渡したパッケージを読み飛ばす
+func (r *SQLRepository) FetchAffectedPackagesWithVersions(ctx context.Context, ecosystem models.EcosystemType, vulPackageId string, fn AffectedPackageFunc) error {
+	query, err := buildQuery(fetchAffectedPackagesWithVersionsSql, ecosystem)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	rows, err := db.Query(sqlString)
+	stmt, err := r.prepare(ctx, query)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer func(rows *sql.Rows) {
-		err := rows.Close()
-		if err != nil {
-			panic(err)
-		}
-	}(rows)
 
-	allReleaseLogs := make(map[string][]models.ReleaseLog, 0)
-	releaseLogs := make([]models.ReleaseLog, 0)
-	nowProjectId := ""
-	for rows.Next() {
-		releaseLog := models.ReleaseLog{
-			PackageType: "package",
+	delivered := 0
+	return r.retry(ctx, func() error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		timer := r.startIdleTimer(cancel)
+		defer timer.stop()
+
+		read := 0
+		emit := func(projectId string, releaseLogs []models.ReleaseLog) error {
+			read++
+			if read <= delivered {
+				return nil
+			}
+			timer.stop()
+			if err := fn(projectId, releaseLogs); err != nil {
+				return &callbackError{err: err}
+			}
+			delivered++
+			timer.reset()
+			return nil
 		}
 
-		err := rows.Scan(
-			&releaseLog.ProjectId,
-			&releaseLog.ProjectName,
-			&releaseLog.VersionId,
-			&releaseLog.VersionNumber,
-			&releaseLog.DependencyRequirements,
-			&releaseLog.PublishedTimestamp,
-		)
+		rows, err := stmt.QueryContext(ctx, vulPackageId, vulPackageId)
 		if err != nil {
-			return nil, err
+			return timer.wrap(err)
 		}
+		defer func(rows *sql.Rows) {
+			err := rows.Close()
+			if err != nil {
+				panic(err)
+			}
+		}(rows)
 
-		if releaseLog.ProjectId != nowProjectId {
-			// 次のパッケージ
-			allReleaseLogs[releaseLog.ProjectId] = releaseLogs
+		// project_id順に並んでいるので、project_idが変わるまでを1つのパッケージのリリース履歴として渡す
+		releaseLogs := make([]models.ReleaseLog, 0)
+		for rows.Next() {
+			timer.reset()
+			var releaseLog models.ReleaseLog
+			var dependencyKind, optionalDependency string
 
-			releaseLogs = []models.ReleaseLog{releaseLog}
-			nowProjectId = releaseLog.ProjectId
-		} else {
-			// 今までと同じパッケージ
+			err := rows.Scan(
+				&releaseLog.ProjectId,
+				&releaseLog.ProjectName,
+				&releaseLog.VersionId,
+				&releaseLog.VersionNumber,
+				&releaseLog.DependencyRequirements,
+				timestamp{&releaseLog.PublishedTimestamp},
+				&releaseLog.PackageType,
+				&dependencyKind,
+				&optionalDependency,
+			)
+			if err != nil {
+				return err
+			}
+			if releaseLog.PackageType == "package" {
+				releaseLog.DependencyKind = models.NormalizeDependencyKind(dependencyKind, optionalDependency)
+			}
+
+			if len(releaseLogs) != 0 && releaseLog.ProjectId != releaseLogs[0].ProjectId {
+				// 次のパッケージ
+				if err := emit(releaseLogs[0].ProjectId, releaseLogs); err != nil {
+					return err
+				}
+				releaseLogs = make([]models.ReleaseLog, 0)
+			}
 			releaseLogs = append(releaseLogs, releaseLog)
 		}
-	}
+		if err := rows.Err(); err != nil {
+			return timer.wrap(err)
+		}
 
-	return allReleaseLogs, nil
+		// 最後のパッケージ
+		if len(releaseLogs) != 0 {
+			return emit(releaseLogs[0].ProjectId, releaseLogs)
+		}
+		return nil
+	})
 }
@@ -2,66 +2,81 @@ package datasource
 
 import (
 	"analyzer/models"
+	"context"
 	"database/sql"
 )
 
 const (
-	mergeTwoPackageReleasesTemplate = `
+	mergeTwoPackageReleasesSql = `
 SELECT d.project_id,d.project_name,d.version_id,v.number AS version_number,d.dependency_requirements,
-	   v.published_timestamp, 'package' AS type
-FROM dependencies_{{.ecosystemType}} d
-INNER JOIN versions_{{.ecosystemType}} v ON d.version_id=v.id
-WHERE d.dependency_project_id={{.vulPackageId}} AND d.project_id={{.packageId}}
+	   v.published_timestamp, 'package' AS type,
+	   COALESCE(d.dependency_kind, '') AS dependency_kind, COALESCE(d.optional_dependency, '') AS optional_dependency
+FROM {dependencies} d
+INNER JOIN {versions} v ON d.version_id=v.id
+WHERE d.dependency_project_id=? AND d.project_id=?
 UNION ALL
 SELECT v.project_id, v.project_name, v.id AS version_id, v.number AS version_number, NULL AS dependency_requirements,
-	   v.published_timestamp, 'vul_package' AS type
-FROM versions_{{.ecosystemType}} v
-WHERE v.project_id={{.vulPackageId}}
-ORDER BY published_timestamp;
+	   v.published_timestamp, 'vul_package' AS type,
+	   '' AS dependency_kind, '' AS optional_dependency
+FROM {versions} v
+WHERE v.project_id=?
+ORDER BY published_timestamp, version_id
 `
 )
 
-func FetchMergedTwoPackageReleasesWithSort(db *sql.DB, ecosystem models.EcosystemType, packageId string, vulPackageId string) ([]models.ReleaseLog, error) {
-	sqlString, err := buildStringWithParamsFromTemplate(mergeTwoPackageReleasesTemplate, map[string]string{
-		"packageId":     packageId,
-		"vulPackageId":  vulPackageId,
-		"ecosystemType": string(ecosystem),
-	})
+func (r *SQLRepository) FetchMergedTwoPackageReleasesWithSort(ctx context.Context, ecosystem models.EcosystemType, packageId string, vulPackageId string) ([]models.ReleaseLog, error) {
+	query, err := buildQuery(mergeTwoPackageReleasesSql, ecosystem)
 	if err != nil {
 		return nil, err
 	}
-
-	rows, err := db.Query(sqlString)
+	stmt, err := r.prepare(ctx, query)
 	if err != nil {
 		return nil, err
 	}
-	defer func(rows *sql.Rows) {
-		err := rows.Close()
+
+	var releaseLogs []models.ReleaseLog
+	err = r.query(ctx, func(ctx context.Context) error {
+		rows, err := stmt.QueryContext(ctx, vulPackageId, packageId, vulPackageId)
 		if err != nil {
-			panic(err)
+			return err
 		}
-	}(rows)
+		defer func(rows *sql.Rows) {
+			err := rows.Close()
+			if err != nil {
+				panic(err)
+			}
+		}(rows)
 
-	// リリース履歴を時系列で取得
-	releaseLogs := make([]models.ReleaseLog, 0)
-	for rows.Next() {
-		var releaseLog models.ReleaseLog
+		// リリース履歴を時系列で取得
+		releaseLogs = make([]models.ReleaseLog, 0)
+		for rows.Next() {
+			var releaseLog models.ReleaseLog
+			var dependencyKind, optionalDependency string
 
-		err := rows.Scan(
-			&releaseLog.ProjectId,
-			&releaseLog.ProjectName,
-			&releaseLog.VersionId,
-			&releaseLog.VersionNumber,
-			&releaseLog.DependencyRequirements,
-			&releaseLog.PublishedTimestamp,
-			&releaseLog.PackageType,
-		)
-		if err != nil {
-			return nil, err
-		}
+			err := rows.Scan(
+				&releaseLog.ProjectId,
+				&releaseLog.ProjectName,
+				&releaseLog.VersionId,
+				&releaseLog.VersionNumber,
+				&releaseLog.DependencyRequirements,
+				timestamp{&releaseLog.PublishedTimestamp},
+				&releaseLog.PackageType,
+				&dependencyKind,
+				&optionalDependency,
+			)
+			if err != nil {
+				return err
+			}
+			if releaseLog.PackageType == "package" {
+				releaseLog.DependencyKind = models.NormalizeDependencyKind(dependencyKind, optionalDependency)
+			}
 
-		releaseLogs = append(releaseLogs, releaseLog)
+			releaseLogs = append(releaseLogs, releaseLog)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
-
 	return releaseLogs, nil
 }
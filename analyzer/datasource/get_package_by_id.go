@@ -2,30 +2,64 @@ package datasource
 
 import (
 	"analyzer/models"
-	"database/sql"
+	"context"
 )
 
 const (
 	getPackageById = `
-SELECT d.source_rank
-FROM projects d
-WHERE d.id='{{.projectId}}'
+SELECT p.id,
+	   COALESCE(p.platform, ''),
+	   COALESCE(p.name, ''),
+	   p.created_timestamp,
+	   COALESCE(p.homepage_url, ''),
+	   COALESCE(p.licenses, ''),
+	   COALESCE(p.repository_url, ''),
+	   COALESCE(p.versions_count, 0),
+	   COALESCE(p.source_rank, 0),
+	   p.latest_release_publish_timestamp,
+	   COALESCE(p.latest_release_number, ''),
+	   COALESCE(p.dependent_projects_count, 0),
+	   COALESCE(p.language, ''),
+	   COALESCE(p.status, ''),
+	   COALESCE(p.dependent_repositories_count, 0),
+	   COALESCE(r.repository_stars_count, 0)
+FROM projects p
+LEFT JOIN project_repository_fields r ON r.id=p.id
+WHERE p.id=?
 LIMIT 1
 `
 )
 
-func GetPackageById(db *sql.DB, projectId string) (*models.Package, error) {
-	sqlString, err := buildStringWithParamsFromTemplate(getPackageById, map[string]string{
-		"projectId": projectId,
-	})
+func (r *SQLRepository) GetPackageById(ctx context.Context, projectId string) (*models.Package, error) {
+	stmt, err := r.prepare(ctx, getPackageById)
 	if err != nil {
 		return nil, err
 	}
 
-	var sourceRank int64
-	if err := db.QueryRow(sqlString).Scan(&sourceRank); err != nil {
+	var p models.Package
+	err = r.query(ctx, func(ctx context.Context) error {
+		return stmt.QueryRowContext(ctx, projectId).Scan(
+			&p.Id,
+			&p.Platform,
+			&p.Name,
+			timestamp{&p.CreatedTimestamp},
+			&p.HomepageUrl,
+			&p.Licenses,
+			&p.RepositoryUrl,
+			&p.VersionsCount,
+			&p.SourceRank,
+			timestamp{&p.LatestReleasePublishTimestamp},
+			&p.LatestReleaseNumber,
+			&p.DependentProjectsCount,
+			&p.Language,
+			&p.Status,
+			&p.DependentRepositoriesCount,
+			&p.Stars,
+		)
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return &models.Package{SourceRank: sourceRank}, nil
+	return &p, nil
 }
@@ -0,0 +1,100 @@
+package datasource
+
+import (
+	"analyzer/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// 1つのクエリで問い合わせるパッケージ名の数
+const packageNamesPerQuery = 1000
+
+const getPackageIdsByNames = `
+SELECT p.name, p.id
+FROM projects p
+WHERE p.platform=? AND %[1]s IN (%[2]s)
+ORDER BY p.id ASC
+`
+
+// normalizedNameColumn はmodels.EcosystemType.NormalizePackageNameと同じ正規化をSQLで行う式を返す
+func normalizedNameColumn(ecosystem models.EcosystemType) string {
+	switch ecosystem {
+	case models.Packagist:
+		return "LOWER(p.name)"
+	case models.Cargo:
+		return "REPLACE(LOWER(p.name), '-', '_')"
+	default:
+		return "p.name"
+	}
+}
+
+func (r *SQLRepository) GetPackageIdsByNames(ctx context.Context, ecosystem models.EcosystemType, projectNames []string) (map[string][]string, error) {
+	if !ecosystem.IsValid() {
+		return nil, fmt.Errorf("got unknown ecosystem type. ecosystem: '%s'", ecosystem)
+	}
+
+	isTarget := make(map[string]bool, len(projectNames))
+	for _, name := range projectNames {
+		isTarget[ecosystem.NormalizePackageName(name)] = true
+	}
+
+	projectIds := make(map[string][]string, len(projectNames))
+	for start := 0; start < len(projectNames); start += packageNamesPerQuery {
+		end := start + packageNamesPerQuery
+		if end > len(projectNames) {
+			end = len(projectNames)
+		}
+		names := projectNames[start:end]
+
+		// 名前の数が毎回変わるので、プリペアドステートメントは使い回さない
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(names)), ",")
+		args := make([]interface{}, 0, len(names)+1)
+		args = append(args, ecosystem.Platform())
+		for _, name := range names {
+			args = append(args, ecosystem.NormalizePackageName(name))
+		}
+
+		query := r.options.Dialect.rebind(fmt.Sprintf(getPackageIdsByNames, normalizedNameColumn(ecosystem), placeholders))
+		err := r.query(ctx, func(ctx context.Context) error {
+			rows, err := r.db.QueryContext(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+			defer func(rows *sql.Rows) {
+				err := rows.Close()
+				if err != nil {
+					panic(err)
+				}
+			}(rows)
+
+			// 再試行したときに同じidを重ねないよう、このチャンクの結果は読み終えてから加える
+			chunk := make(map[string][]string)
+			for rows.Next() {
+				var name, projectId string
+				if err := rows.Scan(&name, &projectId); err != nil {
+					return err
+				}
+				// 照合順序によっては大文字と小文字を区別せずに一致するので、Go側でもう一度正規化して比べる
+				name = ecosystem.NormalizePackageName(name)
+				if !isTarget[name] {
+					continue
+				}
+				chunk[name] = append(chunk[name], projectId)
+			}
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			for name, ids := range chunk {
+				projectIds[name] = append(projectIds[name], ids...)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return projectIds, nil
+}
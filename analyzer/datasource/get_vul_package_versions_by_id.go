@@ -2,59 +2,67 @@ package datasource
 
 import (
 	"analyzer/models"
+	"context"
 	"database/sql"
 )
 
 const (
-	getPackageVersionsByIdSqlTemplate = `
+	getPackageVersionsByIdSql = `
 SELECT v.project_id, v.project_name, v.id AS version_id, v.number AS version_number, NULL AS dependency_requirements,
 	   v.published_timestamp
-FROM versions_{{.ecosystemType}} v
-WHERE v.project_id={{.vulPackageId}}
-ORDER BY published_timestamp;
+FROM {versions} v
+WHERE v.project_id=?
+ORDER BY published_timestamp, version_id
 `
 )
 
-func GetVulPackageVersionsById(db *sql.DB, vulPackageId string, ecosystemType models.EcosystemType) ([]models.ReleaseLog, error) {
-	sqlString, err := buildStringWithParamsFromTemplate(getPackageVersionsByIdSqlTemplate, map[string]string{
-		"vulPackageId":  vulPackageId,
-		"ecosystemType": string(ecosystemType),
-	})
+func (r *SQLRepository) GetVulPackageVersionsById(ctx context.Context, vulPackageId string, ecosystemType models.EcosystemType) ([]models.ReleaseLog, error) {
+	query, err := buildQuery(getPackageVersionsByIdSql, ecosystemType)
 	if err != nil {
 		return nil, err
 	}
-
-	rows, err := db.Query(sqlString)
+	stmt, err := r.prepare(ctx, query)
 	if err != nil {
 		return nil, err
 	}
-	defer func(rows *sql.Rows) {
-		err := rows.Close()
+
+	var releaseLogs []models.ReleaseLog
+	err = r.query(ctx, func(ctx context.Context) error {
+		rows, err := stmt.QueryContext(ctx, vulPackageId)
 		if err != nil {
-			panic(err)
+			return err
 		}
-	}(rows)
+		defer func(rows *sql.Rows) {
+			err := rows.Close()
+			if err != nil {
+				panic(err)
+			}
+		}(rows)
 
-	releaseLogs := make([]models.ReleaseLog, 0)
-	for rows.Next() {
-		releaseLog := models.ReleaseLog{
-			PackageType: "vul_package",
-		}
+		releaseLogs = make([]models.ReleaseLog, 0)
+		for rows.Next() {
+			releaseLog := models.ReleaseLog{
+				PackageType: "vul_package",
+			}
 
-		err := rows.Scan(
-			&releaseLog.ProjectId,
-			&releaseLog.ProjectName,
-			&releaseLog.VersionId,
-			&releaseLog.VersionNumber,
-			&releaseLog.DependencyRequirements,
-			&releaseLog.PublishedTimestamp,
-		)
-		if err != nil {
-			return nil, err
-		}
+			err := rows.Scan(
+				&releaseLog.ProjectId,
+				&releaseLog.ProjectName,
+				&releaseLog.VersionId,
+				&releaseLog.VersionNumber,
+				&releaseLog.DependencyRequirements,
+				timestamp{&releaseLog.PublishedTimestamp},
+			)
+			if err != nil {
+				return err
+			}
 
-		releaseLogs = append(releaseLogs, releaseLog)
+			releaseLogs = append(releaseLogs, releaseLog)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
-
 	return releaseLogs, nil
 }
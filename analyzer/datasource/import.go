@@ -0,0 +1,215 @@
+package datasource
+
+import (
+	"analyzer/librariesio"
+	"analyzer/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+)
+
+const (
+	// 1つのINSERT文でまとめて挿入する行数
+	importRowsPerStatement = 500
+	// 1つのトランザクションで挿入する行数
+	importBatchSize = 100000
+)
+
+// SchemaTables は projects と project_repository_fields テーブルと、ecosystemsの versions_* と dependencies_* テーブルを返す
+func SchemaTables(ecosystems []models.EcosystemType) []Table {
+	tables := []Table{ProjectsTable(), ProjectRepositoryFieldsTable()}
+	for _, ecosystem := range ecosystems {
+		tables = append(tables, VersionsTable(ecosystem), DependenciesTable(ecosystem))
+	}
+	return tables
+}
+
+// CreateSchema はecosystemsのテーブルとインデックスを作る. 既にあるテーブルとインデックスはそのままにする
+func CreateSchema(ctx context.Context, db *sql.DB, dialect Dialect, ecosystems []models.EcosystemType) error {
+	tables := SchemaTables(ecosystems)
+	for _, table := range tables {
+		if _, err := db.ExecContext(ctx, table.CreateTableStatement(dialect)); err != nil {
+			return err
+		}
+	}
+	return createIndexes(ctx, db, dialect, tables)
+}
+
+// Import はLibraries.ioのCSVダンプ(展開したディレクトリかtar.gz)から、ecosystemsのデータだけをテーブルに読み込む.
+// テーブルが無ければ作り、インデックスは読み込んだ後に張る.
+// ctxがキャンセルされたらコミットしていない行は捨てる. コミット済みの行は残るので、読み込み直す場合はテーブルを作り直す
+func Import(ctx context.Context, db *sql.DB, dialect Dialect, path string, ecosystems []models.EcosystemType) error {
+	tables := SchemaTables(ecosystems)
+	for _, table := range tables {
+		if _, err := db.ExecContext(ctx, table.CreateTableStatement(dialect)); err != nil {
+			return err
+		}
+	}
+
+	platforms := make([]string, 0, len(ecosystems))
+	for _, ecosystem := range ecosystems {
+		platforms = append(platforms, ecosystem.Platform())
+	}
+
+	loader := &bulkLoader{ctx: ctx, db: db, dialect: dialect, rows: make(map[string][][]interface{})}
+	if err := librariesio.Walk(path, platforms, func(file librariesio.File, record []string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ecosystem, _ := models.EcosystemTypeFromPlatform(record[1])
+		return loader.insert(tableOf(file, ecosystem), record)
+	}); err != nil {
+		loader.rollback()
+		return err
+	}
+	if err := loader.flush(); err != nil {
+		loader.rollback()
+		return err
+	}
+	log.Printf("%d 行を読み込みました", loader.count)
+
+	return createIndexes(ctx, db, dialect, tables)
+}
+
+// createIndexes はまだ張られていないインデックスを張る
+func createIndexes(ctx context.Context, db *sql.DB, dialect Dialect, tables []Table) error {
+	for _, table := range tables {
+		existing, err := existingIndexes(ctx, db, dialect, table.Name)
+		if err != nil {
+			return err
+		}
+		for _, name := range table.indexNames() {
+			if existing[name] {
+				continue
+			}
+			statement := table.CreateIndexStatement(name)
+			log.Println(statement)
+			if _, err := db.ExecContext(ctx, statement); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func tableOf(file librariesio.File, ecosystem models.EcosystemType) Table {
+	switch file {
+	case librariesio.Versions:
+		return VersionsTable(ecosystem)
+	case librariesio.Dependencies:
+		return DependenciesTable(ecosystem)
+	case librariesio.ProjectsWithRepositoryFields:
+		return ProjectRepositoryFieldsTable()
+	default:
+		return ProjectsTable()
+	}
+}
+
+// bulkLoader はテーブルごとにimportRowsPerStatement行ずつまとめて挿入し、importBatchSize行ごとにコミットする
+type bulkLoader struct {
+	ctx     context.Context
+	db      *sql.DB
+	dialect Dialect
+	tx      *sql.Tx
+	tables  []Table
+	// テーブル名ごとの、まだ挿入していない行
+	rows map[string][][]interface{}
+	// 読み込んだ行数と、そのうちまだコミットしていない行数
+	count       int
+	uncommitted int
+}
+
+func (l *bulkLoader) insert(table Table, record []string) error {
+	if _, ok := l.rows[table.Name]; !ok {
+		l.tables = append(l.tables, table)
+	}
+	l.rows[table.Name] = append(l.rows[table.Name], table.values(record))
+	if len(l.rows[table.Name]) < importRowsPerStatement {
+		return nil
+	}
+
+	if err := l.exec(table); err != nil {
+		return err
+	}
+	if l.uncommitted >= importBatchSize {
+		log.Printf("%d 行を読み込みました", l.count)
+		return l.commit()
+	}
+	return nil
+}
+
+// flush は残っている行を全て挿入してコミットする
+func (l *bulkLoader) flush() error {
+	for _, table := range l.tables {
+		if err := l.exec(table); err != nil {
+			return err
+		}
+	}
+	return l.commit()
+}
+
+func (l *bulkLoader) exec(table Table) error {
+	rows := l.rows[table.Name]
+	if len(rows) == 0 {
+		return nil
+	}
+	if l.tx == nil {
+		tx, err := l.db.BeginTx(l.ctx, nil)
+		if err != nil {
+			return err
+		}
+		l.tx = tx
+	}
+
+	args := make([]interface{}, 0, len(rows)*len(table.Columns))
+	for _, row := range rows {
+		args = append(args, row...)
+	}
+	if _, err := l.tx.ExecContext(l.ctx, l.dialect.rebind(table.InsertStatement(len(rows))), args...); err != nil {
+		return fmt.Errorf("table: %s: %w", table.Name, err)
+	}
+
+	l.count += len(rows)
+	l.uncommitted += len(rows)
+	l.rows[table.Name] = rows[:0]
+	return nil
+}
+
+func (l *bulkLoader) commit() error {
+	if l.tx == nil {
+		return nil
+	}
+	err := l.tx.Commit()
+	l.tx = nil
+	l.uncommitted = 0
+	return err
+}
+
+// rollback はコミットしていない行を捨てる
+func (l *bulkLoader) rollback() {
+	if l.tx == nil {
+		return
+	}
+	if err := l.tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		log.Printf("ロールバックに失敗しました. error: %s", err)
+	}
+	l.tx = nil
+}
+
+// values はCSVダンプの1行を挿入する値にする. 空の値はNULLにし、日時の末尾の " UTC" は取り除く
+func (t Table) values(record []string) []interface{} {
+	values := make([]interface{}, len(t.Columns))
+	for i, c := range t.Columns {
+		value := record[t.recordColumn(i)]
+		if value == "" {
+			values[i] = nil
+		} else if c.Type == "DATETIME" {
+			values[i] = strings.TrimSuffix(value, " UTC")
+		} else {
+			values[i] = value
+		}
+	}
+	return values
+}
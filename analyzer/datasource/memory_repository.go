@@ -0,0 +1,223 @@
+package datasource
+
+import (
+	"analyzer/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Fixture はMemoryRepositoryに読み込むデータ. projects テーブルは models.Package と同じ列を、
+// versions_*, dependencies_* テーブルは必要な列だけを持つ. YAMLでも同じキーで書ける
+//
+//	{
+//	  "projects": [{"id": "1", "platform": "NPM", "name": "lodash", "source_rank": 10}],
+//	  "versions": {"npm": [{"id": "10", "project_id": "1", "project_name": "lodash", "number": "1.0.0", "published_timestamp": "2015-01-01 00:00:00"}]},
+//	  "dependencies": {"npm": [{"project_id": "2", "project_name": "app", "version_id": "20", "dependency_project_id": "1", "dependency_requirements": "^1.0.0"}]}
+//	}
+type Fixture struct {
+	Projects     []models.Package                             `json:"projects" yaml:"projects"`
+	Versions     map[models.EcosystemType][]FixtureVersion    `json:"versions" yaml:"versions"`
+	Dependencies map[models.EcosystemType][]FixtureDependency `json:"dependencies" yaml:"dependencies"`
+}
+
+type FixtureVersion struct {
+	Id                 string `json:"id" yaml:"id"`
+	ProjectId          string `json:"project_id" yaml:"project_id"`
+	ProjectName        string `json:"project_name" yaml:"project_name"`
+	Number             string `json:"number" yaml:"number"`
+	PublishedTimestamp string `json:"published_timestamp" yaml:"published_timestamp"`
+}
+
+type FixtureDependency struct {
+	ProjectId              string `json:"project_id" yaml:"project_id"`
+	ProjectName            string `json:"project_name" yaml:"project_name"`
+	VersionId              string `json:"version_id" yaml:"version_id"`
+	DependencyProjectId    string `json:"dependency_project_id" yaml:"dependency_project_id"`
+	DependencyRequirements string `json:"dependency_requirements" yaml:"dependency_requirements"`
+	DependencyKind         string `json:"dependency_kind" yaml:"dependency_kind"`
+	OptionalDependency     string `json:"optional_dependency" yaml:"optional_dependency"`
+}
+
+// MemoryRepository はフィクスチャファイルから読み込んだデータをメモリ上に持ち、SQLRepositoryと同じ結果を返す
+type MemoryRepository struct {
+	fixture Fixture
+}
+
+func NewMemoryRepository(fixture Fixture) *MemoryRepository {
+	// リリースはSQLRepositoryと同じく公開日時順、同じ日時ならid順に並べておく
+	for _, versions := range fixture.Versions {
+		sort.SliceStable(versions, func(i, j int) bool {
+			if versions[i].PublishedTimestamp != versions[j].PublishedTimestamp {
+				return versions[i].PublishedTimestamp < versions[j].PublishedTimestamp
+			}
+			if len(versions[i].Id) != len(versions[j].Id) {
+				return len(versions[i].Id) < len(versions[j].Id)
+			}
+			return versions[i].Id < versions[j].Id
+		})
+	}
+	return &MemoryRepository{fixture: fixture}
+}
+
+// NewMemoryRepositoryFromFile はフィクスチャファイルを読み込む. 拡張子が .yaml か .yml ならYAML、それ以外はJSONとして読む
+func NewMemoryRepositoryFromFile(path string) (*MemoryRepository, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture Fixture
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(b, &fixture); err != nil {
+			return nil, err
+		}
+	default:
+		if err := json.Unmarshal(b, &fixture); err != nil {
+			return nil, err
+		}
+	}
+	return NewMemoryRepository(fixture), nil
+}
+
+func (r *MemoryRepository) GetPackageIdsByNames(ctx context.Context, ecosystem models.EcosystemType, projectNames []string) (map[string][]string, error) {
+	isTarget := make(map[string]bool, len(projectNames))
+	for _, name := range projectNames {
+		isTarget[ecosystem.NormalizePackageName(name)] = true
+	}
+
+	projectIds := make(map[string][]string)
+	for _, p := range r.fixture.Projects {
+		name := ecosystem.NormalizePackageName(p.Name)
+		if p.Platform != ecosystem.Platform() || !isTarget[name] {
+			continue
+		}
+		projectIds[name] = append(projectIds[name], p.Id)
+	}
+	for _, ids := range projectIds {
+		sortProjectIds(ids)
+	}
+	return projectIds, nil
+}
+
+func (r *MemoryRepository) GetPackageById(ctx context.Context, projectId string) (*models.Package, error) {
+	for _, p := range r.fixture.Projects {
+		if p.Id == projectId {
+			return &p, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *MemoryRepository) FetchAffectedPackagesWithVersions(ctx context.Context, ecosystem models.EcosystemType, vulPackageId string, fn AffectedPackageFunc) error {
+	// 依存元パッケージのリリースごとの、脆弱性パッケージへの依存関係
+	dependencies := make(map[string][]FixtureDependency)
+	affectedPackageIds := make([]string, 0)
+	isAffectedPackage := make(map[string]bool)
+	for _, d := range r.fixture.Dependencies[ecosystem] {
+		if d.DependencyProjectId == vulPackageId {
+			dependencies[d.VersionId] = append(dependencies[d.VersionId], d)
+			if !isAffectedPackage[d.ProjectId] {
+				isAffectedPackage[d.ProjectId] = true
+				affectedPackageIds = append(affectedPackageIds, d.ProjectId)
+			}
+		}
+	}
+
+	// SQLRepositoryと同じく、project_id順に渡す
+	sortProjectIds(affectedPackageIds)
+
+	for _, affectedPackageId := range affectedPackageIds {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		releaseLogs := make([]models.ReleaseLog, 0)
+		for _, v := range r.fixture.Versions[ecosystem] {
+			if v.ProjectId != affectedPackageId {
+				continue
+			}
+
+			if ds, ok := dependencies[v.Id]; ok {
+				for _, d := range ds {
+					releaseLogs = append(releaseLogs, d.releaseLog(v))
+				}
+			} else {
+				releaseLogs = append(releaseLogs, v.releaseLog("not_depending"))
+			}
+		}
+		if len(releaseLogs) == 0 {
+			continue
+		}
+		if err := fn(affectedPackageId, releaseLogs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) GetVulPackageVersionsById(ctx context.Context, vulPackageId string, ecosystemType models.EcosystemType) ([]models.ReleaseLog, error) {
+	releaseLogs := make([]models.ReleaseLog, 0)
+	for _, v := range r.fixture.Versions[ecosystemType] {
+		if v.ProjectId == vulPackageId {
+			releaseLogs = append(releaseLogs, v.releaseLog("vul_package"))
+		}
+	}
+	return releaseLogs, nil
+}
+
+func (r *MemoryRepository) FetchMergedTwoPackageReleasesWithSort(ctx context.Context, ecosystem models.EcosystemType, packageId string, vulPackageId string) ([]models.ReleaseLog, error) {
+	dependencies := make(map[string][]FixtureDependency)
+	for _, d := range r.fixture.Dependencies[ecosystem] {
+		if d.DependencyProjectId == vulPackageId && d.ProjectId == packageId {
+			dependencies[d.VersionId] = append(dependencies[d.VersionId], d)
+		}
+	}
+
+	// リリース履歴を時系列で取得
+	releaseLogs := make([]models.ReleaseLog, 0)
+	for _, v := range r.fixture.Versions[ecosystem] {
+		if ds, ok := dependencies[v.Id]; ok {
+			for _, d := range ds {
+				releaseLogs = append(releaseLogs, d.releaseLog(v))
+			}
+		} else if v.ProjectId == vulPackageId {
+			releaseLogs = append(releaseLogs, v.releaseLog("vul_package"))
+		}
+	}
+	return releaseLogs, nil
+}
+
+func (v FixtureVersion) releaseLog(packageType string) models.ReleaseLog {
+	return models.ReleaseLog{
+		ProjectId:          v.ProjectId,
+		ProjectName:        v.ProjectName,
+		VersionId:          v.Id,
+		VersionNumber:      v.Number,
+		PublishedTimestamp: v.PublishedTimestamp,
+		PackageType:        packageType,
+	}
+}
+
+// sortProjectIds はproject_idをデータベースと同じく数値として昇順に並べる
+func sortProjectIds(projectIds []string) {
+	sort.Slice(projectIds, func(i, j int) bool {
+		if len(projectIds[i]) != len(projectIds[j]) {
+			return len(projectIds[i]) < len(projectIds[j])
+		}
+		return projectIds[i] < projectIds[j]
+	})
+}
+
+// releaseLog はリリースvの、脆弱性パッケージへの依存関係dを表す 'package' のリリース履歴
+func (d FixtureDependency) releaseLog(v FixtureVersion) models.ReleaseLog {
+	releaseLog := v.releaseLog("package")
+	requirement := d.DependencyRequirements
+	releaseLog.DependencyRequirements = &requirement
+	releaseLog.DependencyKind = models.NormalizeDependencyKind(d.DependencyKind, d.OptionalDependency)
+	return releaseLog
+}
@@ -0,0 +1,106 @@
+package datasource
+
+import (
+	"analyzer/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrPackageNotFound はパッケージ名に該当するパッケージが無いことを表す
+var ErrPackageNotFound = errors.New("パッケージが見つかりません")
+
+// AmbiguousPackageNameError はパッケージ名に該当するパッケージが複数あり、どれか1つに決められないことを表す
+type AmbiguousPackageNameError struct {
+	Ecosystem  models.EcosystemType
+	Name       string
+	ProjectIds []string
+}
+
+func (e *AmbiguousPackageNameError) Error() string {
+	return fmt.Sprintf("パッケージ名に該当するパッケージが複数あります. ecosystem: %s, name: %s, project_ids: %s", e.Ecosystem, e.Name, strings.Join(e.ProjectIds, ", "))
+}
+
+// ResolveFailure は解決できなかったパッケージ名と、その理由(ErrPackageNotFound か *AmbiguousPackageNameError)
+type ResolveFailure struct {
+	Name string
+	Err  error
+}
+
+// PackageResolver はパッケージ名を、エコシステムの規則で正規化してからまとめてproject_idに解決する.
+// 一度問い合わせた名前は、見つからなかったものも含めて実行中ずっとキャッシュする
+type PackageResolver struct {
+	repository Repository
+
+	mutex sync.Mutex
+	// エコシステムごとの、正規化したパッケージ名と該当するproject_id. 見つからなかった名前は空
+	cache map[models.EcosystemType]map[string][]string
+}
+
+func NewPackageResolver(repository Repository) *PackageResolver {
+	return &PackageResolver{
+		repository: repository,
+		cache:      make(map[models.EcosystemType]map[string][]string),
+	}
+}
+
+// Resolve はprojectNamesをproject_idに解決する. キャッシュに無い名前だけをまとめて問い合わせる.
+// 解決できた名前とproject_idの対応と、解決できなかった名前(重複は除き、projectNamesの順)を返す
+func (r *PackageResolver) Resolve(ctx context.Context, ecosystem models.EcosystemType, projectNames []string) (map[string]string, []ResolveFailure, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cache, ok := r.cache[ecosystem]
+	if !ok {
+		cache = make(map[string][]string)
+		r.cache[ecosystem] = cache
+	}
+
+	uncached := make([]string, 0)
+	isUncached := make(map[string]bool)
+	for _, name := range projectNames {
+		normalizedName := ecosystem.NormalizePackageName(name)
+		if _, ok := cache[normalizedName]; ok || isUncached[normalizedName] {
+			continue
+		}
+		isUncached[normalizedName] = true
+		uncached = append(uncached, normalizedName)
+	}
+
+	if len(uncached) != 0 {
+		projectIds, err := r.repository.GetPackageIdsByNames(ctx, ecosystem, uncached)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, name := range uncached {
+			cache[name] = projectIds[name]
+		}
+	}
+
+	resolved := make(map[string]string)
+	failures := make([]ResolveFailure, 0)
+	isFailed := make(map[string]bool)
+	for _, name := range projectNames {
+		projectIds := cache[ecosystem.NormalizePackageName(name)]
+		if len(projectIds) == 1 {
+			resolved[name] = projectIds[0]
+			continue
+		}
+		if isFailed[name] {
+			continue
+		}
+		isFailed[name] = true
+
+		if len(projectIds) == 0 {
+			failures = append(failures, ResolveFailure{Name: name, Err: ErrPackageNotFound})
+		} else {
+			failures = append(failures, ResolveFailure{
+				Name: name,
+				Err:  &AmbiguousPackageNameError{Ecosystem: ecosystem, Name: name, ProjectIds: projectIds},
+			})
+		}
+	}
+	return resolved, failures, nil
+}
@@ -0,0 +1,275 @@
+package datasource
+
+import (
+	"analyzer/models"
+	"context"
+	"database/sql"
+	"fmt"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// 同じフィクスチャを読み込んだ各データベースのSQLRepositoryが、MemoryRepositoryと同じ結果を返すかを確かめる.
+// SQLiteは常に、MySQLとPostgreSQLは次の環境変数にDSNを指定したときだけ確かめる.
+// 指定したデータベースのテーブルは作り直すので、テスト用のデータベースを指定すること
+//
+//	ANALYZER_TEST_MYSQL_DSN=root@(localhost:3306)/lib_test
+//	ANALYZER_TEST_POSTGRES_DSN=postgres://postgres@localhost:5432/lib_test?sslmode=disable
+var parityDSNEnvs = map[Dialect]string{
+	MySQL:      "ANALYZER_TEST_MYSQL_DSN",
+	PostgreSQL: "ANALYZER_TEST_POSTGRES_DSN",
+}
+
+var parityEcosystems = []models.EcosystemType{models.Npm, models.Cargo}
+
+// 依存しているパッケージ1つ分の、FetchAffectedPackagesWithVersionsの結果
+type parityAffectedPackage struct {
+	ProjectId   string
+	ReleaseLogs []models.ReleaseLog
+}
+
+var parityCases = []struct {
+	name string
+	run  func(ctx context.Context, r Repository) (interface{}, error)
+}{
+	{"GetPackageIdsByNames/npm", func(ctx context.Context, r Repository) (interface{}, error) {
+		return r.GetPackageIdsByNames(ctx, models.Npm, []string{"lodash", "app", "LODASH", " tool ", "missing"})
+	}},
+	{"GetPackageIdsByNames/packagist", func(ctx context.Context, r Repository) (interface{}, error) {
+		return r.GetPackageIdsByNames(ctx, models.Packagist, []string{"monolog/monolog", "SYMFONY/YAML"})
+	}},
+	{"GetPackageIdsByNames/cargo", func(ctx context.Context, r Repository) (interface{}, error) {
+		return r.GetPackageIdsByNames(ctx, models.Cargo, []string{"serde_json", "App-RS"})
+	}},
+	{"GetPackageById", func(ctx context.Context, r Repository) (interface{}, error) {
+		return r.GetPackageById(ctx, "1")
+	}},
+	{"GetPackageById/null_columns", func(ctx context.Context, r Repository) (interface{}, error) {
+		return r.GetPackageById(ctx, "4")
+	}},
+	{"GetPackageById/not_found", func(ctx context.Context, r Repository) (interface{}, error) {
+		return r.GetPackageById(ctx, "999")
+	}},
+	{"FetchAffectedPackagesWithVersions/npm", func(ctx context.Context, r Repository) (interface{}, error) {
+		return fetchAffectedPackages(ctx, r, models.Npm, "1")
+	}},
+	{"FetchAffectedPackagesWithVersions/transitive", func(ctx context.Context, r Repository) (interface{}, error) {
+		return fetchAffectedPackages(ctx, r, models.Npm, "2")
+	}},
+	{"FetchAffectedPackagesWithVersions/no_dependents", func(ctx context.Context, r Repository) (interface{}, error) {
+		return fetchAffectedPackages(ctx, r, models.Npm, "4")
+	}},
+	{"FetchAffectedPackagesWithVersions/cargo", func(ctx context.Context, r Repository) (interface{}, error) {
+		return fetchAffectedPackages(ctx, r, models.Cargo, "40")
+	}},
+	{"GetVulPackageVersionsById", func(ctx context.Context, r Repository) (interface{}, error) {
+		return r.GetVulPackageVersionsById(ctx, "1", models.Npm)
+	}},
+	{"FetchMergedTwoPackageReleasesWithSort", func(ctx context.Context, r Repository) (interface{}, error) {
+		return r.FetchMergedTwoPackageReleasesWithSort(ctx, models.Npm, "2", "1")
+	}},
+	{"FetchMergedTwoPackageReleasesWithSort/same_timestamp", func(ctx context.Context, r Repository) (interface{}, error) {
+		return r.FetchMergedTwoPackageReleasesWithSort(ctx, models.Npm, "12", "1")
+	}},
+}
+
+func TestSQLRepositoryParity(t *testing.T) {
+	ctx := context.Background()
+	fixture := readParityFixture(t)
+	reference := NewMemoryRepository(fixture)
+
+	for _, dialect := range Dialects {
+		dialect := dialect
+		t.Run(string(dialect), func(t *testing.T) {
+			repository := openParityRepository(t, ctx, dialect, fixture)
+			if err := repository.CheckSchema(ctx, parityEcosystems...); err != nil {
+				t.Fatalf("CheckSchema: %s", err)
+			}
+
+			for _, c := range parityCases {
+				c := c
+				t.Run(c.name, func(t *testing.T) {
+					want, wantErr := c.run(ctx, reference)
+					got, err := c.run(ctx, repository)
+					if fmt.Sprint(err) != fmt.Sprint(wantErr) {
+						t.Fatalf("error = %v, want %v", err, wantErr)
+					}
+					if wantErr != nil {
+						return
+					}
+					if !reflect.DeepEqual(got, want) {
+						t.Errorf("got  %s\nwant %s", formatParityResult(got), formatParityResult(want))
+					}
+				})
+			}
+		})
+	}
+}
+
+func fetchAffectedPackages(ctx context.Context, r Repository, ecosystem models.EcosystemType, vulPackageId string) ([]parityAffectedPackage, error) {
+	packages := make([]parityAffectedPackage, 0)
+	err := r.FetchAffectedPackagesWithVersions(ctx, ecosystem, vulPackageId, func(affectedPackageId string, releaseLogs []models.ReleaseLog) error {
+		packages = append(packages, parityAffectedPackage{ProjectId: affectedPackageId, ReleaseLogs: releaseLogs})
+		return nil
+	})
+	return packages, err
+}
+
+func readParityFixture(t *testing.T) Fixture {
+	t.Helper()
+	r, err := NewMemoryRepositoryFromFile(filepath.Join("testdata", "parity_fixture.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r.fixture
+}
+
+// openParityRepository はdialectのデータベースにフィクスチャを読み込み、SQLRepositoryを返す
+func openParityRepository(t *testing.T, ctx context.Context, dialect Dialect, fixture Fixture) *SQLRepository {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "parity.sqlite")
+	if dialect != SQLite {
+		dsn = os.Getenv(parityDSNEnvs[dialect])
+		if dsn == "" {
+			t.Skipf("%s が指定されていません", parityDSNEnvs[dialect])
+		}
+	}
+
+	db, err := sql.Open(string(dialect), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadParityFixture(t, ctx, db, dialect, fixture)
+
+	if dialect == SQLite {
+		// 解析と同じく、読み込み専用で開き直す
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+		repository, err := OpenSQLiteRepository(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repository
+	}
+
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	options := DefaultSQLOptions()
+	options.Dialect = dialect
+	return NewSQLRepositoryWithOptions(db, options)
+}
+
+func loadParityFixture(t *testing.T, ctx context.Context, db *sql.DB, dialect Dialect, fixture Fixture) {
+	t.Helper()
+
+	for _, table := range SchemaTables(parityEcosystems) {
+		if _, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS "+table.Name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := CreateSchema(ctx, db, dialect, parityEcosystems); err != nil {
+		t.Fatal(err)
+	}
+
+	insert := func(table Table, row map[string]string) {
+		record := make([]string, len(table.Columns))
+		if table.RecordColumns != nil {
+			record = make([]string, table.RecordColumns[len(table.RecordColumns)-1]+1)
+		}
+		for i, c := range table.Columns {
+			record[table.recordColumn(i)] = row[c.Name]
+		}
+		if _, err := db.ExecContext(ctx, dialect.rebind(table.InsertStatement(1)), table.values(record)...); err != nil {
+			t.Fatalf("table: %s: %s", table.Name, err)
+		}
+	}
+
+	for _, p := range fixture.Projects {
+		insert(ProjectsTable(), map[string]string{
+			"id":                               p.Id,
+			"platform":                         p.Platform,
+			"name":                             p.Name,
+			"created_timestamp":                p.CreatedTimestamp,
+			"homepage_url":                     p.HomepageUrl,
+			"licenses":                         p.Licenses,
+			"repository_url":                   p.RepositoryUrl,
+			"versions_count":                   strconv.FormatInt(p.VersionsCount, 10),
+			"source_rank":                      strconv.FormatInt(p.SourceRank, 10),
+			"latest_release_publish_timestamp": p.LatestReleasePublishTimestamp,
+			"latest_release_number":            p.LatestReleaseNumber,
+			"dependent_projects_count":         strconv.FormatInt(p.DependentProjectsCount, 10),
+			"language":                         p.Language,
+			"status":                           p.Status,
+			"dependent_repositories_count":     strconv.FormatInt(p.DependentRepositoriesCount, 10),
+		})
+		if p.Stars != 0 {
+			insert(ProjectRepositoryFieldsTable(), map[string]string{
+				"id":                     p.Id,
+				"repository_stars_count": strconv.FormatInt(p.Stars, 10),
+			})
+		}
+	}
+	for _, ecosystem := range parityEcosystems {
+		for _, v := range fixture.Versions[ecosystem] {
+			insert(VersionsTable(ecosystem), map[string]string{
+				"id":                  v.Id,
+				"platform":            ecosystem.Platform(),
+				"project_name":        v.ProjectName,
+				"project_id":          v.ProjectId,
+				"number":              v.Number,
+				"published_timestamp": v.PublishedTimestamp,
+			})
+		}
+		for i, d := range fixture.Dependencies[ecosystem] {
+			insert(DependenciesTable(ecosystem), map[string]string{
+				"id":                      strconv.Itoa(i + 1),
+				"platform":                ecosystem.Platform(),
+				"project_name":            d.ProjectName,
+				"project_id":              d.ProjectId,
+				"version_id":              d.VersionId,
+				"dependency_kind":         d.DependencyKind,
+				"optional_dependency":     d.OptionalDependency,
+				"dependency_requirements": d.DependencyRequirements,
+				"dependency_project_id":   d.DependencyProjectId,
+			})
+		}
+	}
+}
+
+// formatParityResult はポインタの先も比べられるように、結果をJSONに近い形で表示する
+func formatParityResult(v interface{}) string {
+	switch r := v.(type) {
+	case []models.ReleaseLog:
+		return formatReleaseLogs(r)
+	case []parityAffectedPackage:
+		s := ""
+		for _, p := range r {
+			s += fmt.Sprintf("\n  %s: %s", p.ProjectId, formatReleaseLogs(p.ReleaseLogs))
+		}
+		return s
+	default:
+		return fmt.Sprintf("%+v", v)
+	}
+}
+
+func formatReleaseLogs(releaseLogs []models.ReleaseLog) string {
+	s := ""
+	for _, l := range releaseLogs {
+		requirement := "<nil>"
+		if l.DependencyRequirements != nil {
+			requirement = *l.DependencyRequirements
+		}
+		s += fmt.Sprintf("\n    {%s %s %s %s %s %s %s}", l.VersionId, l.VersionNumber, l.PublishedTimestamp, l.PackageType, requirement, l.DependencyKind, l.ProjectName)
+	}
+	return s
+}
@@ -0,0 +1,24 @@
+package datasource
+
+import (
+	"analyzer/models"
+	"context"
+)
+
+// AffectedPackageFunc は脆弱性パッケージに依存しているパッケージ1つ分の、公開日時順のリリース履歴を受け取る.
+// errorを返すとそこで取得をやめ、そのerrorを呼び出し元に返す
+type AffectedPackageFunc func(affectedPackageId string, releaseLogs []models.ReleaseLog) error
+
+// Repository は解析に必要なパッケージとリリース履歴の取得元.
+// どのメソッドもctxがキャンセルされたら取得をやめ、ctxのエラーを返す
+type Repository interface {
+	// GetPackageIdsByNames はprojectsテーブルから、パッケージ名をエコシステムの規則で正規化して一致するproject_idを全て取得する.
+	// 結果は正規化したパッケージ名ごとのproject_id(昇順)で、見つからなかった名前は含めない
+	GetPackageIdsByNames(ctx context.Context, ecosystem models.EcosystemType, projectNames []string) (map[string][]string, error)
+	GetPackageById(ctx context.Context, projectId string) (*models.Package, error)
+	// FetchAffectedPackagesWithVersions は脆弱性パッケージに依存しているパッケージを1つずつ、project_id順にfnに渡す.
+	// 全てのパッケージをメモリに載せないように、データベースから読みながら渡す
+	FetchAffectedPackagesWithVersions(ctx context.Context, ecosystem models.EcosystemType, vulPackageId string, fn AffectedPackageFunc) error
+	GetVulPackageVersionsById(ctx context.Context, vulPackageId string, ecosystemType models.EcosystemType) ([]models.ReleaseLog, error)
+	FetchMergedTwoPackageReleasesWithSort(ctx context.Context, ecosystem models.EcosystemType, packageId string, vulPackageId string) ([]models.ReleaseLog, error)
+}
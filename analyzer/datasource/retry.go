@@ -0,0 +1,154 @@
+package datasource
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// 再試行すれば成功する可能性があるMySQLのエラー番号
+var transientMySQLErrors = map[uint16]bool{
+	1040: true, // ER_CON_COUNT_ERROR: Too many connections
+	1053: true, // ER_SERVER_SHUTDOWN
+	1203: true, // ER_TOO_MANY_USER_CONNECTIONS
+	1205: true, // ER_LOCK_WAIT_TIMEOUT
+	1213: true, // ER_LOCK_DEADLOCK
+}
+
+// 再試行すれば成功する可能性があるPostgreSQLのSQLSTATE. 08で始まる接続のエラーも含める
+var transientSQLStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"53300": true, // too_many_connections
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now
+}
+
+// isTransientError は接続の切断やデッドロックなど、再試行すれば成功する可能性があるエラーかどうかを返す.
+// タイムアウトやキャンセルによるエラーは再試行しない
+func isTransientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return transientMySQLErrors[mysqlErr.Number]
+	}
+	// PostgreSQLのドライバ(lib/pq, pgx)のエラーはSQLSTATEを返すので、ドライバをimportせずに判定する
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		state := stateErr.SQLState()
+		return transientSQLStates[state] || strings.HasPrefix(state, "08")
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// SQLiteのドライバはcgoが必要なのでimportせず、メッセージで判定する
+	message := err.Error()
+	return strings.Contains(message, "database is locked") || strings.Contains(message, "database table is locked")
+}
+
+// callbackError は呼び出し側から渡された関数が返したエラー. 再試行せずにそのまま返す
+type callbackError struct {
+	err error
+}
+
+func (e *callbackError) Error() string {
+	return e.err.Error()
+}
+
+// retry はfnを、一時的なエラーで失敗したらRetryAttempts回まで待ち時間を倍にしながら試す.
+// ctxがキャンセルされたら待たずにやめる
+func (r *SQLRepository) retry(ctx context.Context, fn func() error) error {
+	backoff := r.options.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		var cbErr *callbackError
+		if errors.As(err, &cbErr) {
+			return cbErr.err
+		}
+		if err == nil || attempt >= r.options.RetryAttempts || ctx.Err() != nil || !isTransientError(err) {
+			return err
+		}
+
+		log.Printf("一時的なエラーが発生しました. %s後に再試行します. attempt: %d/%d, error: %s", backoff, attempt, r.options.RetryAttempts, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// query はfnを、QueryTimeoutで打ち切られるcontextを渡して、一時的なエラーなら再試行しながら実行する
+func (r *SQLRepository) query(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.retry(ctx, func() error {
+		ctx, cancel := r.withTimeout(ctx)
+		defer cancel()
+		return fn(ctx)
+	})
+}
+
+func (r *SQLRepository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.options.QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.options.QueryTimeout)
+}
+
+// idleTimer はQueryTimeoutの間resetされなければcancelを呼んで、1行ずつ読んでいるクエリを打ち切る.
+// QueryTimeoutが0なら何もしない
+type idleTimer struct {
+	timer   *time.Timer
+	timeout time.Duration
+	fired   int32
+}
+
+func (r *SQLRepository) startIdleTimer(cancel context.CancelFunc) *idleTimer {
+	t := &idleTimer{timeout: r.options.QueryTimeout}
+	if t.timeout > 0 {
+		t.timer = time.AfterFunc(t.timeout, func() {
+			atomic.StoreInt32(&t.fired, 1)
+			cancel()
+		})
+	}
+	return t
+}
+
+func (t *idleTimer) stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+func (t *idleTimer) reset() {
+	if t.timer != nil {
+		t.timer.Reset(t.timeout)
+	}
+}
+
+// wrap はタイマーで打ち切ったことによるエラーを、context.DeadlineExceededとして返す
+func (t *idleTimer) wrap(err error) error {
+	if atomic.LoadInt32(&t.fired) == 1 {
+		return fmt.Errorf("クエリが %s 以上応答しませんでした: %w", t.timeout, context.DeadlineExceeded)
+	}
+	return err
+}
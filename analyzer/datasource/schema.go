@@ -0,0 +1,206 @@
+package datasource
+
+import (
+	"analyzer/models"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Column はテーブルの列. Libraries.ioのCSVダンプと同じ順番で並べる
+type Column struct {
+	Name string
+	Type string
+}
+
+// Table はLibraries.ioのCSVダンプ1ファイル分のテーブル
+type Table struct {
+	Name    string
+	Columns []Column
+	// Columns のそれぞれがCSVダンプの何列目か. nilなら Columns と同じ順番で並んでいる
+	RecordColumns []int
+	// インデックス名と、インデックスを張る列
+	Indexes map[string][]string
+	// CSVダンプに含まれていないことがあり、データが無くても解析できるかどうか
+	Optional bool
+}
+
+var projectsColumns = []Column{
+	{"id", "INT PRIMARY KEY"},
+	{"platform", "VARCHAR(255)"},
+	{"name", "VARCHAR(255)"},
+	{"created_timestamp", "DATETIME"},
+	{"updated_timestamp", "DATETIME"},
+	{"description", "TEXT"},
+	{"keywords", "TEXT"},
+	{"homepage_url", "TEXT"},
+	{"licenses", "TEXT"},
+	{"repository_url", "TEXT"},
+	{"versions_count", "INT"},
+	{"source_rank", "INT"},
+	{"latest_release_publish_timestamp", "DATETIME"},
+	{"latest_release_number", "VARCHAR(255)"},
+	{"package_manager_id", "INT"},
+	{"dependent_projects_count", "INT"},
+	{"language", "VARCHAR(255)"},
+	{"status", "VARCHAR(255)"},
+	{"last_synced_timestamp", "DATETIME"},
+	{"dependent_repositories_count", "INT"},
+	{"repository_id", "INT"},
+}
+
+// projects_with_repository_fields のダンプは projects と同じ21列の後にリポジトリの列が続く.
+// 解析に使うリポジトリのスター数だけを読み込む
+var projectRepositoryFieldsColumns = []Column{
+	{"id", "INT PRIMARY KEY"},
+	{"repository_stars_count", "INT"},
+}
+
+var projectRepositoryFieldsRecordColumns = []int{0, 30}
+
+var versionsColumns = []Column{
+	{"id", "INT PRIMARY KEY"},
+	{"platform", "VARCHAR(255)"},
+	{"project_name", "VARCHAR(255)"},
+	{"project_id", "INT"},
+	{"number", "VARCHAR(255)"},
+	{"published_timestamp", "DATETIME"},
+	{"created_timestamp", "DATETIME"},
+	{"updated_timestamp", "DATETIME"},
+}
+
+var dependenciesColumns = []Column{
+	{"id", "INT PRIMARY KEY"},
+	{"platform", "VARCHAR(255)"},
+	{"project_name", "VARCHAR(255)"},
+	{"project_id", "INT"},
+	{"version_number", "VARCHAR(255)"},
+	{"version_id", "INT"},
+	{"dependency_name", "VARCHAR(255)"},
+	{"dependency_platform", "VARCHAR(255)"},
+	{"dependency_kind", "VARCHAR(255)"},
+	{"optional_dependency", "VARCHAR(255)"},
+	{"dependency_requirements", "VARCHAR(255)"},
+	{"dependency_project_id", "INT"},
+}
+
+// ProjectsTable は全てのエコシステムのパッケージを持つ projects テーブル
+func ProjectsTable() Table {
+	return Table{
+		Name:    "projects",
+		Columns: projectsColumns,
+		Indexes: map[string][]string{
+			"idx_projects_platform_name": {"platform", "name"},
+		},
+	}
+}
+
+// ProjectRepositoryFieldsTable は projects_with_repository_fields のダンプから読み込む、パッケージのリポジトリの情報.
+// 古いダンプには無いので、データが無ければスター数は0になる
+func ProjectRepositoryFieldsTable() Table {
+	return Table{
+		Name:          "project_repository_fields",
+		Columns:       projectRepositoryFieldsColumns,
+		RecordColumns: projectRepositoryFieldsRecordColumns,
+		Optional:      true,
+	}
+}
+
+// VersionsTable はエコシステムごとの versions_* テーブル
+func VersionsTable(ecosystem models.EcosystemType) Table {
+	name := "versions_" + string(ecosystem)
+	return Table{
+		Name:    name,
+		Columns: versionsColumns,
+		Indexes: map[string][]string{
+			"idx_" + name + "_project_id": {"project_id", "published_timestamp"},
+		},
+	}
+}
+
+// DependenciesTable はエコシステムごとの dependencies_* テーブル
+func DependenciesTable(ecosystem models.EcosystemType) Table {
+	name := "dependencies_" + string(ecosystem)
+	return Table{
+		Name:    name,
+		Columns: dependenciesColumns,
+		Indexes: map[string][]string{
+			"idx_" + name + "_dependency_project_id": {"dependency_project_id", "project_id", "version_id"},
+			"idx_" + name + "_version_id":            {"version_id", "dependency_project_id"},
+			"idx_" + name + "_project_name":          {"project_name"},
+		},
+	}
+}
+
+// CreateTableStatement はテーブルを作るSQLを返す
+func (t Table) CreateTableStatement(dialect Dialect) string {
+	columns := make([]string, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		columns = append(columns, fmt.Sprintf("    %s %s", c.Name, c.columnType(dialect)))
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n)", t.Name, strings.Join(columns, ",\n"))
+}
+
+// CreateIndexStatements はインデックスを全て作るSQLを返す
+func (t Table) CreateIndexStatements() []string {
+	statements := make([]string, 0, len(t.Indexes))
+	for _, name := range t.indexNames() {
+		statements = append(statements, t.CreateIndexStatement(name))
+	}
+	return statements
+}
+
+// CreateIndexStatement はインデックスを1つ作るSQLを返す
+func (t Table) CreateIndexStatement(name string) string {
+	return fmt.Sprintf("CREATE INDEX %s ON %s (%s)", name, t.Name, strings.Join(t.Indexes[name], ", "))
+}
+
+// indexNames はインデックス名を名前順で返す
+func (t Table) indexNames() []string {
+	names := make([]string, 0, len(t.Indexes))
+	for name := range t.Indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InsertStatement はrows行をまとめて挿入するSQLを返す
+func (t Table) InsertStatement(rows int) string {
+	names := make([]string, 0, len(t.Columns))
+	placeholders := make([]string, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		names = append(names, c.Name)
+		placeholders = append(placeholders, "?")
+	}
+	row := "(" + strings.Join(placeholders, ", ") + ")"
+
+	values := make([]string, 0, rows)
+	for i := 0; i < rows; i++ {
+		values = append(values, row)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", t.Name, strings.Join(names, ", "), strings.Join(values, ", "))
+}
+
+// recordColumn はi番目の列がCSVダンプの何列目かを返す
+func (t Table) recordColumn(i int) int {
+	if t.RecordColumns == nil {
+		return i
+	}
+	return t.RecordColumns[i]
+}
+
+func (c Column) columnType(dialect Dialect) string {
+	if c.Type != "DATETIME" {
+		return c.Type
+	}
+	switch dialect {
+	case SQLite:
+		// go-sqlite3はDATETIME型の列をtime.Timeとして返す. 読み込んだ文字列のまま持っておけるようにTEXT型にしておく
+		return "TEXT"
+	case PostgreSQL:
+		return "TIMESTAMP"
+	default:
+		return c.Type
+	}
+}
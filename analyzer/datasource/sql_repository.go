@@ -0,0 +1,89 @@
+package datasource
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// SQLRepository はLibraries.ioのデータセットを取り込んだデータベース(MySQLまたはimporterで作ったSQLite)からデータを取得する
+type SQLRepository struct {
+	db      *sql.DB
+	options SQLOptions
+
+	statementsMutex sync.Mutex
+	statements      map[string]*sql.Stmt
+}
+
+// SQLOptions はSQLRepositoryのクエリの実行方法の設定
+type SQLOptions struct {
+	Dialect Dialect
+	// 1つのクエリにかけられる時間. 0なら無制限.
+	// 1行ずつ渡すクエリでは、データベースから次の行が届くまでの時間に適用する
+	QueryTimeout time.Duration
+	// 一時的なエラーで失敗したときに試す回数(最初の1回を含む)と、再試行までの待ち時間(試すたびに倍にする)
+	RetryAttempts int
+	RetryBackoff  time.Duration
+}
+
+// DefaultSQLOptions はMySQLに対して、タイムアウト無しで一時的なエラーを3回まで試す設定
+func DefaultSQLOptions() SQLOptions {
+	return SQLOptions{
+		Dialect:       MySQL,
+		RetryAttempts: 3,
+		RetryBackoff:  time.Second,
+	}
+}
+
+// NewSQLRepository はMySQLのデータベースからデータを取得する
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return NewSQLRepositoryWithOptions(db, DefaultSQLOptions())
+}
+
+// NewSQLRepositoryWithOptions はoptionsの設定でデータベースからデータを取得する
+func NewSQLRepositoryWithOptions(db *sql.DB, options SQLOptions) *SQLRepository {
+	if options.RetryAttempts < 1 {
+		options.RetryAttempts = 1
+	}
+	return &SQLRepository{
+		db:         db,
+		options:    options,
+		statements: make(map[string]*sql.Stmt),
+	}
+}
+
+// OpenSQLiteRepository はimporterで作ったSQLiteのファイルを読み取り専用で開く.
+// SQLiteのドライバ(github.com/mattn/go-sqlite3)は呼び出し側でimportしておく必要がある
+func OpenSQLiteRepository(path string) (*SQLRepository, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(string(SQLite), fmt.Sprintf("file:%s?mode=ro", path))
+	if err != nil {
+		return nil, err
+	}
+	options := DefaultSQLOptions()
+	options.Dialect = SQLite
+	return NewSQLRepositoryWithOptions(db, options), nil
+}
+
+// prepare はクエリをプリペアドステートメントにする. 一度プリペアしたステートメントは使い回す
+func (r *SQLRepository) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	r.statementsMutex.Lock()
+	defer r.statementsMutex.Unlock()
+
+	if stmt, ok := r.statements[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := r.db.PrepareContext(ctx, r.options.Dialect.rebind(query))
+	if err != nil {
+		return nil, err
+	}
+	r.statements[query] = stmt
+	return stmt, nil
+}
@@ -1,20 +1,20 @@
 package datasource
 
 import (
-	"bytes"
-	"text/template"
+	"analyzer/models"
+	"fmt"
+	"strings"
 )
 
-func buildStringWithParamsFromTemplate(templateString string, params map[string]string) (string, error) {
-	tpl, err := template.New("").Parse(templateString)
-	if err != nil {
-		return "", err
+// buildQuery はクエリ中の {dependencies} と {versions} を、エコシステムごとのテーブル名に置き換える.
+// テーブル名はプレースホルダーで渡せないので、検証済みのエコシステムからのみ組み立てる
+func buildQuery(query string, ecosystem models.EcosystemType) (string, error) {
+	if !ecosystem.IsValid() {
+		return "", fmt.Errorf("got unknown ecosystem type. ecosystem: '%s'", ecosystem)
 	}
 
-	buf := new(bytes.Buffer)
-	if err := tpl.Execute(buf, params); err != nil {
-		return "", err
-	}
-
-	return buf.String(), nil
+	return strings.NewReplacer(
+		"{dependencies}", "dependencies_"+string(ecosystem),
+		"{versions}", "versions_"+string(ecosystem),
+	).Replace(query), nil
 }
@@ -0,0 +1,52 @@
+package exposure
+
+import (
+	"time"
+)
+
+// DisclosureSplit は影響期間を脆弱性の公開(advisoryのpublished_at)の前後に分けたもの
+type DisclosureSplit struct {
+	// 脆弱性が公開される前に影響を受けていた時間
+	PreDisclosureDuration time.Duration
+	// 脆弱性が公開された後に影響を受けていた時間. 公開後も影響が続いていて期間の終わりが分からない場合はnil
+	PostDisclosureDuration *time.Duration
+	// 脆弱性の公開から影響を受け終わるまでの時間. 公開前に影響を受け終わっている場合はnil
+	DisclosureToEndDuration *time.Duration
+}
+
+// SplitAtDisclosure は影響期間を脆弱性の公開日時で分割する.
+// VulEndDateがnilの場合は期間の終わりが分からないため、公開日時までを公開前の期間とし、公開後の期間はnilとする
+func (interval Interval) SplitAtDisclosure(disclosedAt time.Time) DisclosureSplit {
+	start := *interval.VulStartDate
+	if interval.VulEndDate == nil {
+		if start.Before(disclosedAt) {
+			return DisclosureSplit{PreDisclosureDuration: disclosedAt.Sub(start)}
+		}
+		// 公開された後に影響を受け始めていて、まだ影響が続いている
+		return DisclosureSplit{}
+	}
+
+	end := *interval.VulEndDate
+	if !end.After(disclosedAt) {
+		// 公開される前に影響を受け終わっている
+		var postDisclosure time.Duration
+		return DisclosureSplit{PreDisclosureDuration: end.Sub(start), PostDisclosureDuration: &postDisclosure}
+	}
+
+	disclosureToEnd := end.Sub(disclosedAt)
+	if !start.Before(disclosedAt) {
+		// 公開された後に影響を受け始めている
+		postDisclosure := end.Sub(start)
+		return DisclosureSplit{
+			PostDisclosureDuration:  &postDisclosure,
+			DisclosureToEndDuration: &disclosureToEnd,
+		}
+	}
+
+	postDisclosure := end.Sub(disclosedAt)
+	return DisclosureSplit{
+		PreDisclosureDuration:   disclosedAt.Sub(start),
+		PostDisclosureDuration:  &postDisclosure,
+		DisclosureToEndDuration: &disclosureToEnd,
+	}
+}
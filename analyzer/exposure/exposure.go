@@ -0,0 +1,544 @@
+package exposure
+
+import (
+	"analyzer/models"
+	"analyzer/sv"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const publishedTimestampLayout = "2006-01-02 15:04:05"
+
+// Interval は依存元パッケージが脆弱性の影響を受けていた期間
+type Interval struct {
+	PackageId                     string
+	VulPackageId                  string
+	VulStartDate                  *time.Time
+	VulEndDate                    *time.Time
+	CompliantType                 models.CompliantType
+	VulStartDependencyRequirement string
+	// 影響を受け始めたときに解決されていた脆弱性パッケージのバージョン
+	VulStartVersion sv.Version
+	// 影響を受け始めたときの依存元パッケージのバージョン
+	PackageStartVersion sv.Version
+	// 影響を受けていた依存元パッケージの最新バージョン
+	VulEndVersion sv.Version
+	// 解析時点で影響が続いていて、VulEndDateが打ち切られた時刻かどうか(右側打ち切り)
+	Censored bool
+	// 影響を受けている間(終わりのリリースを含む)に初めて公開された、脆弱性が修正されたバージョンの情報.
+	// 修正版が公開されていなければnil
+	FixReleaseDate *time.Time
+	FixVersion     sv.Version
+	// 影響を受けていたときの依存関係制約が修正版を許容していたかどうか
+	FixAdmitted bool
+	FixStatus   FixStatus
+	// 影響を受け終わった理由
+	EndCause EndCause
+	// 影響を受け終わる直前と直後の依存関係制約. 依存関係制約が変わっていない場合は同じ値になる.
+	// 影響が続いている場合や依存をやめた場合、EndDependencyRequirementは空
+	PreEndDependencyRequirement string
+	EndDependencyRequirement    string
+	// 影響を受け始めたときの依存関係の種類
+	DependencyKind models.DependencyKind
+}
+
+// EndCause は影響を受け終わった理由
+type EndCause string
+
+const (
+	// 依存元パッケージが依存関係制約を変更した
+	EndCauseConstraintChanged EndCause = "constraint_changed"
+	// 脆弱性パッケージが既存の依存関係制約の範囲内で修正版をリリースした
+	EndCauseUpstreamFix EndCause = "upstream_fix"
+	// 依存元パッケージが脆弱性パッケージへの依存をやめた
+	EndCauseDependencyDropped EndCause = "dependency_dropped"
+	// 解析時点でまだ影響を受けている
+	EndCauseOpen EndCause = "open"
+)
+
+// FixStatus は影響を受けている間の修正版の状況
+type FixStatus string
+
+const (
+	// 影響を受けている間に修正版が存在しなかった
+	FixNotAvailable FixStatus = "no_fix"
+	// 修正版が公開され、依存関係制約によって自動的に採用された
+	FixAutoPicked FixStatus = "auto_picked"
+	// 修正版が存在していたが、依存関係制約によって採用されなかった
+	FixBlockedByConstraint FixStatus = "blocked_by_constraint"
+	// 修正版は依存関係制約に許容されていたが、後に公開された同じ系列の脆弱性を含むバージョンに解決されていた
+	FixAdmittedNotPicked FixStatus = "admitted_not_picked"
+)
+
+// Options は影響期間の解析方法の設定
+type Options struct {
+	// AsOf より後に公開されたリリースは無視し、この時点で続いている期間はこの時点で打ち切る.
+	// ゼロ値の場合は全てのリリースを使い、続いている期間のVulEndDateはnilになる
+	AsOf time.Time
+	// DependencyKinds に含まれない種類の依存関係は、依存していない(not_depending)ものとして扱う.
+	// nilの場合は全ての種類の依存関係を使う
+	DependencyKinds []models.DependencyKind
+	// Ecosystem の規則で依存関係制約と脆弱性のバージョン範囲を解釈する(sv.NewConstraint)
+	Ecosystem models.EcosystemType
+}
+
+// ParseAsOf は解析時点の指定を、日付(2006-01-02)・リリース日時と同じ形式・RFC3339のいずれかとして解釈する
+func ParseAsOf(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", publishedTimestampLayout, time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("解析時点の形式が不正です. as-of: '%s'", s)
+}
+
+// MergeReleaseLogs は公開日時順に並んだ2つのリリース履歴を、公開日時順を保ったまま1つにまとめる.
+// 片方を使い切った後は、もう片方の残りのリリースをそのまま続ける
+func MergeReleaseLogs(a []models.ReleaseLog, b []models.ReleaseLog) []models.ReleaseLog {
+	i := 0
+	j := 0
+	newReleaseLogs := make([]models.ReleaseLog, len(a)+len(b))
+	for k := 0; k < len(a)+len(b); k++ {
+		if i < len(a) && (j >= len(b) || a[i].PublishedTimestamp < b[j].PublishedTimestamp) {
+			newReleaseLogs[k] = a[i]
+			i++
+		} else {
+			newReleaseLogs[k] = b[j]
+			j++
+		}
+	}
+
+	return newReleaseLogs
+}
+
+// Analyze は依存元パッケージのリリース履歴(package, not_depending)と脆弱性パッケージのリリース履歴(vul_package)から、
+// 依存元パッケージが脆弱性の影響を受けていた期間を求める.
+// not_depending は脆弱性パッケージに依存していない依存元パッケージのリリース
+func Analyze(packageReleaseLogs []models.ReleaseLog, vulPackageReleaseLogs []models.ReleaseLog, vulConstraint string, opts Options) ([]Interval, error) {
+	if !opts.AsOf.IsZero() {
+		packageReleaseLogs = filterReleaseLogsUntil(packageReleaseLogs, opts.AsOf)
+		vulPackageReleaseLogs = filterReleaseLogsUntil(vulPackageReleaseLogs, opts.AsOf)
+	}
+	packageReleaseLogs = filterDependencyKinds(packageReleaseLogs, opts.DependencyKinds)
+	if len(packageReleaseLogs) == 0 || len(vulPackageReleaseLogs) == 0 {
+		return []Interval{}, nil
+	}
+
+	vc, err := sv.NewConstraint(opts.Ecosystem, vulConstraint)
+	if err != nil {
+		return nil, err
+	}
+
+	packageId := packageReleaseLogs[0].ProjectId
+	vulPackageId := vulPackageReleaseLogs[0].ProjectId
+	releaseLogs := MergeReleaseLogs(packageReleaseLogs, vulPackageReleaseLogs)
+
+	// 脆弱性の影響を受けていた期間を特定
+	// 変数: 脆弱性の始まりと終わりのバージョン
+	// 依存元パッケージの最新のリリースが脆弱性パッケージに依存しているかどうか
+	isDepending := false
+	nowAffectedVulnerability := false
+	var affectedVulnerabilityStartDate *time.Time
+
+	// 脆弱性の影響を受け始めたときの情報
+	var vulStartConstraint string
+	var vulStartVersion sv.Version
+	var packageStartVersion sv.Version
+	var dependencyKind models.DependencyKind
+
+	results := make([]Interval, 0)
+
+	// 影響を受けていた期間を確定させる. endIndexのリリースは含めない
+	closeInterval := func(endDate *time.Time, endIndex int, endCause EndCause) error {
+		compliantType, err := sv.CheckCompliantSemVer(opts.Ecosystem, vulStartConstraint, vulStartVersion)
+		if err != nil {
+			return err
+		}
+
+		// 脆弱性が存在していた最新バージョンを取得したいので、自分のリリースを入れる必要はない
+		vulEndVersion, err := findLatestPackageVersion(opts.Ecosystem, releaseLogs[0:endIndex])
+		if err != nil {
+			return err
+		}
+
+		fix, err := findFixRelease(opts.Ecosystem, releaseLogs, endIndex, endCause, vulStartVersion, vc)
+		if err != nil {
+			return err
+		}
+
+		preEndRequirements, err := findLatestPackageDependencyRequirements(releaseLogs[0:endIndex])
+		if err != nil {
+			return err
+		}
+		endRequirements := ""
+		if endCause == EndCauseConstraintChanged {
+			endRequirements = *releaseLogs[endIndex].DependencyRequirements
+		} else if endCause == EndCauseUpstreamFix {
+			endRequirements = preEndRequirements
+		}
+
+		results = append(results, Interval{
+			PackageId:                     packageId,
+			VulPackageId:                  vulPackageId,
+			VulStartDate:                  affectedVulnerabilityStartDate,
+			VulEndDate:                    endDate,
+			CompliantType:                 compliantType,
+			VulStartDependencyRequirement: vulStartConstraint,
+			VulStartVersion:               vulStartVersion,
+			PackageStartVersion:           packageStartVersion,
+			VulEndVersion:                 vulEndVersion,
+			Censored:                      endCause == EndCauseOpen,
+			FixReleaseDate:                fix.date,
+			FixVersion:                    fix.version,
+			FixAdmitted:                   fix.admitted,
+			FixStatus:                     fix.status,
+			EndCause:                      endCause,
+			PreEndDependencyRequirement:   preEndRequirements,
+			EndDependencyRequirement:      endRequirements,
+			DependencyKind:                dependencyKind,
+		})
+
+		// 状態を初期化
+		nowAffectedVulnerability = false
+		affectedVulnerabilityStartDate = nil
+		vulStartConstraint = ""
+		vulStartVersion = nil
+		packageStartVersion = nil
+		dependencyKind = ""
+		return nil
+	}
+
+	for i, releaseLog := range releaseLogs {
+		var isAffectedVulnerability bool
+		var requirements string
+		var v sv.Version
+
+		if releaseLog.PackageType == "package" {
+			// 依存元のパッケージ
+			requirements = *releaseLog.DependencyRequirements
+			c, err := sv.NewConstraint(opts.Ecosystem, requirements)
+			if err != nil {
+				return nil, err
+			}
+			if c.Resolvable() {
+				isAffectedVulnerability, v, err = isAffectedVulnerabilityWithPackage(opts.Ecosystem, c, releaseLogs[0:i], vc)
+				if err != nil {
+					return nil, err
+				}
+				isDepending = true
+			} else {
+				// gitリポジトリやローカルのファイルなど、レジストリのバージョンに解決されない指定は依存していないものとして扱う
+				isAffectedVulnerability = false
+				isDepending = false
+			}
+		} else if releaseLog.PackageType == "not_depending" {
+			// 脆弱性パッケージに依存していない依存元のパッケージ
+			isAffectedVulnerability = false
+			isDepending = false
+		} else if releaseLog.PackageType == "vul_package" {
+			// 依存先のパッケージ(脆弱性を発生させたパッケージ)
+			// beforeReleaseには自分のリリースも入れる必要がある
+			isAffectedVulnerability, requirements, v, err = isAffectedVulnerabilityWithVulPackage(opts.Ecosystem, isDepending, releaseLogs[0:i+1], vc)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			return nil, fmt.Errorf("got unknown type of package. type: %s", releaseLog.PackageType)
+		}
+
+		d, err := time.Parse(publishedTimestampLayout, releaseLog.PublishedTimestamp)
+		if err != nil {
+			return nil, err
+		}
+
+		if isAffectedVulnerability {
+			if !nowAffectedVulnerability {
+				// 脆弱性の影響を受けはじめた
+				affectedVulnerabilityStartDate = &d
+				nowAffectedVulnerability = true
+
+				// 脆弱性パッケージのリリースで影響を受け始めた場合も、そのときの依存関係制約を記録する
+				vulStartConstraint = requirements
+				vulStartVersion = v
+				packageStartVersion, err = findLatestPackageVersion(opts.Ecosystem, releaseLogs[0:i+1])
+				if err != nil {
+					return nil, err
+				}
+				dependencyKind = findLatestPackageDependencyKind(releaseLogs[0 : i+1])
+			}
+			// 継続して脆弱性の影響を受けている
+		} else if nowAffectedVulnerability {
+			// 脆弱性の影響を受け終わった
+			endCause := EndCauseUpstreamFix
+			if releaseLog.PackageType == "package" {
+				endCause = EndCauseConstraintChanged
+			} else if releaseLog.PackageType == "not_depending" {
+				endCause = EndCauseDependencyDropped
+			}
+			if err := closeInterval(&d, i, endCause); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if nowAffectedVulnerability {
+		// 解析時点で影響が続いている
+		var endDate *time.Time
+		if !opts.AsOf.IsZero() {
+			endDate = &opts.AsOf
+		}
+		if err := closeInterval(endDate, len(releaseLogs), EndCauseOpen); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+type fixRelease struct {
+	date     *time.Time
+	version  sv.Version
+	admitted bool
+	status   FixStatus
+}
+
+// 影響を受け始めてから終わりのリリース(endIndex)までに公開された、vulStartVersionより新しく脆弱性を含まない修正版を探す.
+// 影響を受け始める前に公開されていた修正版も対象にする.
+// 修正版のリリースで影響を受け終わった場合は、そのリリースを自動的に採用された修正版とする.
+// それ以外の場合は、影響を受けていたときの依存関係制約が許容する最初の修正版を優先し、無ければ最初の修正版を採用されなかった修正版とする
+func findFixRelease(ecosystem models.EcosystemType, releaseLogs []models.ReleaseLog, endIndex int, endCause EndCause, vulStartVersion sv.Version, vulConstraint sv.Constraint) (fixRelease, error) {
+	if endCause == EndCauseUpstreamFix {
+		v, err := sv.NewVersion(ecosystem, releaseLogs[endIndex].VersionNumber)
+		if err != nil {
+			return fixRelease{}, err
+		}
+		d, err := time.Parse(publishedTimestampLayout, releaseLogs[endIndex].PublishedTimestamp)
+		if err != nil {
+			return fixRelease{}, err
+		}
+		return fixRelease{
+			date:     &d,
+			version:  v,
+			admitted: true,
+			status:   FixAutoPicked,
+		}, nil
+	}
+
+	// 影響を受けていたときの依存関係制約
+	requirements, err := findLatestPackageDependencyRequirements(releaseLogs[0:endIndex])
+	if err != nil {
+		return fixRelease{}, err
+	}
+	c, err := sv.NewConstraint(ecosystem, requirements)
+	if err != nil {
+		return fixRelease{}, err
+	}
+
+	var blocked *fixRelease
+	for i := 0; i < endIndex; i++ {
+		if releaseLogs[i].PackageType != "vul_package" {
+			continue
+		}
+		v, err := sv.NewVersion(ecosystem, releaseLogs[i].VersionNumber)
+		if err != nil {
+			continue
+		}
+		if v.Compare(vulStartVersion) <= 0 || vulConstraint.Check(v) {
+			continue
+		}
+
+		d, err := time.Parse(publishedTimestampLayout, releaseLogs[i].PublishedTimestamp)
+		if err != nil {
+			return fixRelease{}, err
+		}
+		if c.Check(v) {
+			// 許容されていたが、後に公開された脆弱性を含むバージョンに解決されていた
+			return fixRelease{
+				date:     &d,
+				version:  v,
+				admitted: true,
+				status:   FixAdmittedNotPicked,
+			}, nil
+		}
+		if blocked == nil {
+			blocked = &fixRelease{
+				date:     &d,
+				version:  v,
+				admitted: false,
+				status:   FixBlockedByConstraint,
+			}
+		}
+	}
+	if blocked != nil {
+		return *blocked, nil
+	}
+	return fixRelease{status: FixNotAvailable}, nil
+}
+
+// SplitAtFix は影響期間を、修正版が存在しなかった時間と、修正版が存在したが依存関係制約によって採用されなかった時間に分ける.
+// VulEndDateがnilの場合は期間の終わりが分からないため、修正版が公開されるまでの時間のみを求める
+func (interval Interval) SplitAtFix() (noFixDuration time.Duration, blockedDuration time.Duration) {
+	start := *interval.VulStartDate
+	if interval.FixReleaseDate == nil {
+		if interval.VulEndDate == nil {
+			return 0, 0
+		}
+		return interval.VulEndDate.Sub(start), 0
+	}
+
+	fixDate := *interval.FixReleaseDate
+	if fixDate.Before(start) {
+		fixDate = start
+	}
+	noFixDuration = fixDate.Sub(start)
+	if interval.VulEndDate != nil && interval.FixStatus == FixBlockedByConstraint {
+		blockedDuration = interval.VulEndDate.Sub(fixDate)
+	}
+	return noFixDuration, blockedDuration
+}
+
+// DerivedConstraint は影響を受けていた期間の依存元パッケージのバージョン範囲を、
+// 依存元パッケージを新たな脆弱性パッケージとみなしたときの脆弱性制約として返す.
+// 期間ごとの範囲はecosystemの規則で区間の和集合にまとめ、重なる範囲を1つにする
+func DerivedConstraint(ecosystem models.EcosystemType, intervals []Interval) string {
+	constraints := make([]string, 0, len(intervals))
+	for _, interval := range intervals {
+		constraints = append(constraints, fmt.Sprintf(">=%s <=%s", interval.PackageStartVersion, interval.VulEndVersion))
+	}
+	joined := strings.Join(constraints, " || ")
+
+	// 空の範囲の書き方はエコシステムに無いので、範囲にできない場合と同じくそのまま返す
+	r, err := sv.NewRange(ecosystem, joined)
+	if err != nil || r.IsEmpty() {
+		return joined
+	}
+	return r.String()
+}
+
+// filterDependencyKinds はkindsに含まれない種類の依存関係を持つリリースを、依存していないリリース(not_depending)にする.
+// 1つのリリースが複数の種類で依存している場合(続けて並んでいる同じversion_idのリリース)は、
+// kindsに含まれる中で models.DependencyKinds の順に最も配布物に含まれやすい種類の1つだけを残す
+func filterDependencyKinds(releaseLogs []models.ReleaseLog, kinds []models.DependencyKind) []models.ReleaseLog {
+	priorities := make(map[models.DependencyKind]int)
+	for i, kind := range models.DependencyKinds {
+		if kinds != nil && !containsDependencyKind(kinds, kind) {
+			continue
+		}
+		priorities[kind] = len(models.DependencyKinds) - i
+	}
+
+	filtered := make([]models.ReleaseLog, 0, len(releaseLogs))
+	for _, releaseLog := range releaseLogs {
+		if releaseLog.PackageType == "package" && priorities[releaseLog.DependencyKind] == 0 {
+			releaseLog.PackageType = "not_depending"
+			releaseLog.DependencyRequirements = nil
+			releaseLog.DependencyKind = ""
+		}
+
+		last := len(filtered) - 1
+		if last < 0 || filtered[last].VersionId != releaseLog.VersionId {
+			filtered = append(filtered, releaseLog)
+		} else if priorities[releaseLog.DependencyKind] > priorities[filtered[last].DependencyKind] {
+			// 同じリリースの別の種類の依存関係
+			filtered[last] = releaseLog
+		}
+	}
+	return filtered
+}
+
+func containsDependencyKind(kinds []models.DependencyKind, kind models.DependencyKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// asOf より後に公開されたリリースを取り除く
+func filterReleaseLogsUntil(releaseLogs []models.ReleaseLog, asOf time.Time) []models.ReleaseLog {
+	until := asOf.UTC().Format(publishedTimestampLayout)
+	for i, releaseLog := range releaseLogs {
+		if releaseLog.PublishedTimestamp > until {
+			return releaseLogs[0:i]
+		}
+	}
+	return releaseLogs
+}
+
+func findLatestPackageVersion(ecosystem models.EcosystemType, beforeReleases []models.ReleaseLog) (sv.Version, error) {
+	for i := len(beforeReleases) - 1; i >= 0; i-- {
+		if beforeReleases[i].PackageType == "package" {
+			v, err := sv.NewVersion(ecosystem, beforeReleases[i].VersionNumber)
+			if err != nil {
+				return nil, err
+			}
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("最新の依存関係制約が見つかりませんでした")
+}
+
+func findLatestPackageDependencyRequirements(beforeReleases []models.ReleaseLog) (string, error) {
+	for i := len(beforeReleases) - 1; i >= 0; i-- {
+		if beforeReleases[i].PackageType == "package" {
+			return *beforeReleases[i].DependencyRequirements, nil
+		}
+	}
+	return "", fmt.Errorf("最新の依存関係制約が見つかりませんでした")
+}
+
+func findLatestPackageDependencyKind(beforeReleases []models.ReleaseLog) models.DependencyKind {
+	for i := len(beforeReleases) - 1; i >= 0; i-- {
+		if beforeReleases[i].PackageType == "package" {
+			return beforeReleases[i].DependencyKind
+		}
+	}
+	return ""
+}
+
+func isAffectedVulnerabilityWithVulPackage(ecosystem models.EcosystemType, isDepending bool, beforeReleases []models.ReleaseLog, vulConstraint sv.Constraint) (bool, string, sv.Version, error) {
+	if !isDepending {
+		return false, "", nil, nil
+	}
+
+	requirements, err := findLatestPackageDependencyRequirements(beforeReleases)
+	if err != nil {
+		return false, "", nil, err
+	}
+
+	c, err := sv.NewConstraint(ecosystem, requirements)
+	if err != nil {
+		return false, "", nil, err
+	}
+	isAffected, v, err := isAffectedVulnerabilityWithPackage(ecosystem, c, beforeReleases, vulConstraint)
+	if err != nil {
+		return false, "", nil, err
+	}
+	return isAffected, requirements, v, nil
+}
+
+func isAffectedVulnerabilityWithPackage(ecosystem models.EcosystemType, c sv.Constraint, beforeReleases []models.ReleaseLog, vulConstraint sv.Constraint) (bool, sv.Version, error) {
+	for i := len(beforeReleases) - 1; i >= 0; i-- {
+		// 最新から順に制約を満たすかどうか確認する
+		if beforeReleases[i].PackageType != "vul_package" {
+			continue
+		}
+
+		v, err := sv.NewVersion(ecosystem, beforeReleases[i].VersionNumber)
+		if err != nil {
+			return false, nil, err
+		}
+
+		// 制約を満たしていなければ脆弱かどうかを調べる必要がないのでcontinue
+		if !c.Check(v) {
+			continue
+		}
+
+		// 脆弱性影響を受けているかどうか
+		return vulConstraint.Check(v), v, nil
+	}
+	// 一度もヒットしなければ、エラー
+	return false, nil, fmt.Errorf("制約を満たすバージョンが見つかりませんでした. 制約: '%s'", c)
+}
@@ -0,0 +1,487 @@
+package exposure
+
+import (
+	"analyzer/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 依存元パッケージ(project_id 2)と脆弱性パッケージ(project_id 1)のリリース履歴で影響期間を確かめる
+
+func dependentRelease(version string, published string, requirements string, kind models.DependencyKind) models.ReleaseLog {
+	return models.ReleaseLog{
+		ProjectId:              "2",
+		ProjectName:            "app",
+		VersionId:              "2-" + version,
+		VersionNumber:          version,
+		DependencyRequirements: &requirements,
+		PublishedTimestamp:     published,
+		PackageType:            "package",
+		DependencyKind:         kind,
+	}
+}
+
+func notDependingRelease(version string, published string) models.ReleaseLog {
+	return models.ReleaseLog{
+		ProjectId:          "2",
+		ProjectName:        "app",
+		VersionId:          "2-" + version,
+		VersionNumber:      version,
+		PublishedTimestamp: published,
+		PackageType:        "not_depending",
+	}
+}
+
+func vulRelease(version string, published string) models.ReleaseLog {
+	return models.ReleaseLog{
+		ProjectId:          "1",
+		ProjectName:        "lodash",
+		VersionId:          "1-" + version,
+		VersionNumber:      version,
+		PublishedTimestamp: published,
+		PackageType:        "vul_package",
+	}
+}
+
+func mustTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(publishedTimestampLayout, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// intervalSummary はテストで比べる影響期間の値. 日時とバージョンは文字列にする
+type intervalSummary struct {
+	Start, End                   string
+	Censored                     bool
+	EndCause                     EndCause
+	StartRequirement             string
+	StartVersion, PackageVersion string
+	EndVersion                   string
+	FixVersion                   string
+	FixAdmitted                  bool
+	FixStatus                    FixStatus
+	PreEndRequirement            string
+	EndRequirement               string
+	DependencyKind               models.DependencyKind
+}
+
+func summarize(intervals []Interval) []intervalSummary {
+	summaries := make([]intervalSummary, 0, len(intervals))
+	for _, interval := range intervals {
+		s := intervalSummary{
+			Start:             interval.VulStartDate.Format(publishedTimestampLayout),
+			Censored:          interval.Censored,
+			EndCause:          interval.EndCause,
+			StartRequirement:  interval.VulStartDependencyRequirement,
+			StartVersion:      interval.VulStartVersion.String(),
+			PackageVersion:    interval.PackageStartVersion.String(),
+			EndVersion:        interval.VulEndVersion.String(),
+			FixAdmitted:       interval.FixAdmitted,
+			FixStatus:         interval.FixStatus,
+			PreEndRequirement: interval.PreEndDependencyRequirement,
+			EndRequirement:    interval.EndDependencyRequirement,
+			DependencyKind:    interval.DependencyKind,
+		}
+		if interval.VulEndDate != nil {
+			s.End = interval.VulEndDate.Format(publishedTimestampLayout)
+		}
+		if interval.FixVersion != nil {
+			s.FixVersion = interval.FixVersion.String()
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
+
+var analyzeCases = []struct {
+	name          string
+	packages      []models.ReleaseLog
+	vulPackages   []models.ReleaseLog
+	vulConstraint string
+	opts          Options
+	want          []intervalSummary
+}{
+	{
+		name: "upstream_fix",
+		packages: []models.ReleaseLog{
+			dependentRelease("1.0.0", "2020-02-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+		},
+		vulPackages: []models.ReleaseLog{
+			vulRelease("1.0.0", "2020-01-01 00:00:00"),
+			vulRelease("1.0.1", "2020-03-01 00:00:00"),
+		},
+		vulConstraint: "<1.0.1",
+		want: []intervalSummary{{
+			Start: "2020-02-01 00:00:00", End: "2020-03-01 00:00:00", EndCause: EndCauseUpstreamFix,
+			StartRequirement: "^1.0.0", StartVersion: "1.0.0", PackageVersion: "1.0.0", EndVersion: "1.0.0",
+			FixVersion: "1.0.1", FixAdmitted: true, FixStatus: FixAutoPicked,
+			PreEndRequirement: "^1.0.0", EndRequirement: "^1.0.0", DependencyKind: models.DependencyKindRuntime,
+		}},
+	},
+	{
+		name: "constraint_changed",
+		packages: []models.ReleaseLog{
+			dependentRelease("1.0.0", "2020-02-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+			dependentRelease("1.1.0", "2020-03-01 00:00:00", "^2.0.0", models.DependencyKindRuntime),
+		},
+		vulPackages: []models.ReleaseLog{
+			vulRelease("1.0.0", "2020-01-01 00:00:00"),
+			vulRelease("2.0.0", "2020-01-15 00:00:00"),
+		},
+		vulConstraint: "<2.0.0",
+		want: []intervalSummary{{
+			Start: "2020-02-01 00:00:00", End: "2020-03-01 00:00:00", EndCause: EndCauseConstraintChanged,
+			StartRequirement: "^1.0.0", StartVersion: "1.0.0", PackageVersion: "1.0.0", EndVersion: "1.0.0",
+			FixVersion: "2.0.0", FixAdmitted: false, FixStatus: FixBlockedByConstraint,
+			PreEndRequirement: "^1.0.0", EndRequirement: "^2.0.0", DependencyKind: models.DependencyKindRuntime,
+		}},
+	},
+	{
+		name: "dependency_dropped",
+		packages: []models.ReleaseLog{
+			dependentRelease("1.0.0", "2020-02-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+			notDependingRelease("1.1.0", "2020-03-01 00:00:00"),
+		},
+		vulPackages: []models.ReleaseLog{
+			vulRelease("1.0.0", "2020-01-01 00:00:00"),
+		},
+		vulConstraint: "<1.0.1",
+		want: []intervalSummary{{
+			Start: "2020-02-01 00:00:00", End: "2020-03-01 00:00:00", EndCause: EndCauseDependencyDropped,
+			StartRequirement: "^1.0.0", StartVersion: "1.0.0", PackageVersion: "1.0.0", EndVersion: "1.0.0",
+			FixStatus: FixNotAvailable, PreEndRequirement: "^1.0.0", DependencyKind: models.DependencyKindRuntime,
+		}},
+	},
+	{
+		name: "open",
+		packages: []models.ReleaseLog{
+			dependentRelease("1.0.0", "2020-02-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+		},
+		vulPackages: []models.ReleaseLog{
+			vulRelease("1.0.0", "2020-01-01 00:00:00"),
+		},
+		vulConstraint: "<1.0.1",
+		want: []intervalSummary{{
+			Start: "2020-02-01 00:00:00", Censored: true, EndCause: EndCauseOpen,
+			StartRequirement: "^1.0.0", StartVersion: "1.0.0", PackageVersion: "1.0.0", EndVersion: "1.0.0",
+			FixStatus: FixNotAvailable, PreEndRequirement: "^1.0.0", DependencyKind: models.DependencyKindRuntime,
+		}},
+	},
+	{
+		// 解析時点より後の修正版は無視し、解析時点で打ち切る
+		name: "open/as_of",
+		packages: []models.ReleaseLog{
+			dependentRelease("1.0.0", "2020-02-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+		},
+		vulPackages: []models.ReleaseLog{
+			vulRelease("1.0.0", "2020-01-01 00:00:00"),
+			vulRelease("1.0.1", "2020-07-01 00:00:00"),
+		},
+		vulConstraint: "<1.0.1",
+		opts:          Options{AsOf: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)},
+		want: []intervalSummary{{
+			Start: "2020-02-01 00:00:00", End: "2020-06-01 00:00:00", Censored: true, EndCause: EndCauseOpen,
+			StartRequirement: "^1.0.0", StartVersion: "1.0.0", PackageVersion: "1.0.0", EndVersion: "1.0.0",
+			FixStatus: FixNotAvailable, PreEndRequirement: "^1.0.0", DependencyKind: models.DependencyKindRuntime,
+		}},
+	},
+	{
+		// 脆弱性パッケージのリリースで影響を受け始めた期間も、そのときの依存関係制約を記録する
+		name: "started_by_vul_package",
+		packages: []models.ReleaseLog{
+			dependentRelease("1.0.0", "2020-01-15 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+		},
+		vulPackages: []models.ReleaseLog{
+			vulRelease("1.0.0", "2020-01-01 00:00:00"),
+			vulRelease("1.1.0", "2020-02-01 00:00:00"),
+			vulRelease("1.1.1", "2020-03-01 00:00:00"),
+		},
+		vulConstraint: ">=1.1.0 <1.1.1",
+		want: []intervalSummary{{
+			Start: "2020-02-01 00:00:00", End: "2020-03-01 00:00:00", EndCause: EndCauseUpstreamFix,
+			StartRequirement: "^1.0.0", StartVersion: "1.1.0", PackageVersion: "1.0.0", EndVersion: "1.0.0",
+			FixVersion: "1.1.1", FixAdmitted: true, FixStatus: FixAutoPicked,
+			PreEndRequirement: "^1.0.0", EndRequirement: "^1.0.0", DependencyKind: models.DependencyKindRuntime,
+		}},
+	},
+	{
+		// 脆弱性パッケージの最後のリリースより後の依存元パッケージのリリースも解析する
+		name: "releases_after_last_vul_release",
+		packages: []models.ReleaseLog{
+			dependentRelease("1.0.0", "2020-02-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+			dependentRelease("1.1.0", "2020-03-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+			notDependingRelease("1.2.0", "2020-04-01 00:00:00"),
+		},
+		vulPackages: []models.ReleaseLog{
+			vulRelease("1.0.0", "2020-01-01 00:00:00"),
+		},
+		vulConstraint: "<1.0.1",
+		want: []intervalSummary{{
+			Start: "2020-02-01 00:00:00", End: "2020-04-01 00:00:00", EndCause: EndCauseDependencyDropped,
+			StartRequirement: "^1.0.0", StartVersion: "1.0.0", PackageVersion: "1.0.0", EndVersion: "1.1.0",
+			FixStatus: FixNotAvailable, PreEndRequirement: "^1.0.0", DependencyKind: models.DependencyKindRuntime,
+		}},
+	},
+	{
+		name: "not_affected",
+		packages: []models.ReleaseLog{
+			dependentRelease("1.0.0", "2020-02-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+		},
+		vulPackages: []models.ReleaseLog{
+			vulRelease("1.0.1", "2020-01-01 00:00:00"),
+		},
+		vulConstraint: "<1.0.1",
+		want:          []intervalSummary{},
+	},
+	{
+		// DependencyKinds に含まれない種類の依存関係は依存していないものとして扱う
+		name: "dependency_kind/excluded",
+		packages: []models.ReleaseLog{
+			dependentRelease("1.0.0", "2020-02-01 00:00:00", "^1.0.0", models.DependencyKindDev),
+		},
+		vulPackages: []models.ReleaseLog{
+			vulRelease("1.0.0", "2020-01-01 00:00:00"),
+		},
+		vulConstraint: "<1.0.1",
+		opts:          Options{DependencyKinds: []models.DependencyKind{models.DependencyKindRuntime}},
+		want:          []intervalSummary{},
+	},
+	{
+		// devの依存関係をやめると、runtimeだけを対象にする場合は依存をやめたことになる
+		name: "dependency_kind/dropped",
+		packages: []models.ReleaseLog{
+			dependentRelease("1.0.0", "2020-02-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+			dependentRelease("1.1.0", "2020-03-01 00:00:00", "^1.0.0", models.DependencyKindDev),
+		},
+		vulPackages: []models.ReleaseLog{
+			vulRelease("1.0.0", "2020-01-01 00:00:00"),
+		},
+		vulConstraint: "<1.0.1",
+		opts:          Options{DependencyKinds: []models.DependencyKind{models.DependencyKindRuntime}},
+		want: []intervalSummary{{
+			Start: "2020-02-01 00:00:00", End: "2020-03-01 00:00:00", EndCause: EndCauseDependencyDropped,
+			StartRequirement: "^1.0.0", StartVersion: "1.0.0", PackageVersion: "1.0.0", EndVersion: "1.0.0",
+			FixStatus: FixNotAvailable, PreEndRequirement: "^1.0.0", DependencyKind: models.DependencyKindRuntime,
+		}},
+	},
+	{
+		// 1つのリリースが複数の種類で依存している場合は、配布物に含まれやすい種類を記録する
+		name: "dependency_kind/same_release",
+		packages: []models.ReleaseLog{
+			dependentRelease("1.0.0", "2020-02-01 00:00:00", "^1.0.0", models.DependencyKindDev),
+			dependentRelease("1.0.0", "2020-02-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+		},
+		vulPackages: []models.ReleaseLog{
+			vulRelease("1.0.0", "2020-01-01 00:00:00"),
+		},
+		vulConstraint: "<1.0.1",
+		want: []intervalSummary{{
+			Start: "2020-02-01 00:00:00", Censored: true, EndCause: EndCauseOpen,
+			StartRequirement: "^1.0.0", StartVersion: "1.0.0", PackageVersion: "1.0.0", EndVersion: "1.0.0",
+			FixStatus: FixNotAvailable, PreEndRequirement: "^1.0.0", DependencyKind: models.DependencyKindRuntime,
+		}},
+	},
+}
+
+func TestAnalyze(t *testing.T) {
+	for _, c := range analyzeCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			opts := c.opts
+			opts.Ecosystem = models.Npm
+			intervals, err := Analyze(c.packages, c.vulPackages, c.vulConstraint, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := summarize(intervals); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got  %+v\nwant %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestFixRelease(t *testing.T) {
+	// 2つの範囲を持つ脆弱性. 1.x系は1.2.5、2.x系は2.0.3で修正された
+	const multiBranch = "<1.2.5 || >=2.0.0 <2.0.3"
+	cases := []struct {
+		name          string
+		packages      []models.ReleaseLog
+		vulPackages   []models.ReleaseLog
+		vulConstraint string
+		wantVersion   string
+		wantAdmitted  bool
+		wantStatus    FixStatus
+	}{
+		{
+			name: "single_branch",
+			packages: []models.ReleaseLog{
+				dependentRelease("1.0.0", "2020-02-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+			},
+			vulPackages: []models.ReleaseLog{
+				vulRelease("1.0.0", "2020-01-01 00:00:00"),
+				vulRelease("1.0.1", "2020-03-01 00:00:00"),
+			},
+			vulConstraint: "<1.0.1",
+			wantVersion:   "1.0.1", wantAdmitted: true, wantStatus: FixAutoPicked,
+		},
+		{
+			// 先に公開された2.0.3は ^1.0.0 が許容しないので、影響を終わらせた1.2.5を修正版とする
+			name: "multi_branch",
+			packages: []models.ReleaseLog{
+				dependentRelease("1.0.0", "2020-02-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+			},
+			vulPackages: []models.ReleaseLog{
+				vulRelease("1.2.4", "2020-01-01 00:00:00"),
+				vulRelease("2.0.2", "2020-01-15 00:00:00"),
+				vulRelease("2.0.3", "2020-03-01 00:00:00"),
+				vulRelease("1.2.5", "2020-04-01 00:00:00"),
+			},
+			vulConstraint: multiBranch,
+			wantVersion:   "1.2.5", wantAdmitted: true, wantStatus: FixAutoPicked,
+		},
+		{
+			// ~1.0.0 から >=1.0.0 に広げたときには、許容される1.2.5より後に公開された、脆弱性を含む2.0.2に解決されていた
+			name: "multi_branch/admitted_not_picked",
+			packages: []models.ReleaseLog{
+				dependentRelease("1.0.0", "2020-02-01 00:00:00", "~1.0.0", models.DependencyKindRuntime),
+				dependentRelease("1.1.0", "2020-03-15 00:00:00", ">=1.0.0", models.DependencyKindRuntime),
+			},
+			vulPackages: []models.ReleaseLog{
+				vulRelease("1.0.0", "2020-01-01 00:00:00"),
+				vulRelease("1.2.5", "2020-03-01 00:00:00"),
+				vulRelease("2.0.2", "2020-03-10 00:00:00"),
+			},
+			vulConstraint: multiBranch,
+			wantVersion:   "1.2.5", wantAdmitted: true, wantStatus: FixAdmittedNotPicked,
+		},
+		{
+			name: "no_fix",
+			packages: []models.ReleaseLog{
+				dependentRelease("1.0.0", "2020-02-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+				notDependingRelease("1.1.0", "2020-03-01 00:00:00"),
+			},
+			vulPackages: []models.ReleaseLog{
+				vulRelease("1.0.0", "2020-01-01 00:00:00"),
+			},
+			vulConstraint: "<2.0.0",
+			wantStatus:    FixNotAvailable,
+		},
+		{
+			name: "blocked",
+			packages: []models.ReleaseLog{
+				dependentRelease("1.0.0", "2020-02-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+				dependentRelease("2.0.0", "2020-04-01 00:00:00", "^2.0.0", models.DependencyKindRuntime),
+			},
+			vulPackages: []models.ReleaseLog{
+				vulRelease("1.0.0", "2020-01-01 00:00:00"),
+				vulRelease("2.0.0", "2020-03-01 00:00:00"),
+			},
+			vulConstraint: "<2.0.0",
+			wantVersion:   "2.0.0", wantAdmitted: false, wantStatus: FixBlockedByConstraint,
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			intervals, err := Analyze(c.packages, c.vulPackages, c.vulConstraint, Options{Ecosystem: models.Npm})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(intervals) != 1 {
+				t.Fatalf("got %d intervals, want 1: %+v", len(intervals), summarize(intervals))
+			}
+			got := summarize(intervals)[0]
+			if got.FixVersion != c.wantVersion || got.FixAdmitted != c.wantAdmitted || got.FixStatus != c.wantStatus {
+				t.Errorf("fix = (%q, %v, %s), want (%q, %v, %s)", got.FixVersion, got.FixAdmitted, got.FixStatus, c.wantVersion, c.wantAdmitted, c.wantStatus)
+			}
+		})
+	}
+}
+
+func TestMergeReleaseLogs(t *testing.T) {
+	a := []models.ReleaseLog{
+		dependentRelease("1.0.0", "2020-02-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+		dependentRelease("1.1.0", "2020-04-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+		dependentRelease("1.2.0", "2020-05-01 00:00:00", "^1.0.0", models.DependencyKindRuntime),
+	}
+	b := []models.ReleaseLog{
+		vulRelease("1.0.0", "2020-01-01 00:00:00"),
+		vulRelease("1.0.1", "2020-03-01 00:00:00"),
+	}
+
+	var got []string
+	for _, releaseLog := range MergeReleaseLogs(a, b) {
+		got = append(got, releaseLog.VersionId)
+	}
+	// bを使い切った後のaのリリースも残す
+	want := []string{"1-1.0.0", "2-1.0.0", "1-1.0.1", "2-1.1.0", "2-1.2.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSplitAtFix(t *testing.T) {
+	start := mustTime(t, "2020-01-01 00:00:00")
+	fix := mustTime(t, "2020-01-11 00:00:00")
+	beforeStart := mustTime(t, "2019-12-01 00:00:00")
+	end := mustTime(t, "2020-01-31 00:00:00")
+	day := 24 * time.Hour
+
+	cases := []struct {
+		name        string
+		interval    Interval
+		wantNoFix   time.Duration
+		wantBlocked time.Duration
+	}{
+		{"no_fix", Interval{VulStartDate: &start, VulEndDate: &end, FixStatus: FixNotAvailable}, 30 * day, 0},
+		{"no_fix/open", Interval{VulStartDate: &start, FixStatus: FixNotAvailable}, 0, 0},
+		{"auto_picked", Interval{VulStartDate: &start, VulEndDate: &fix, FixReleaseDate: &fix, FixStatus: FixAutoPicked}, 10 * day, 0},
+		{"blocked", Interval{VulStartDate: &start, VulEndDate: &end, FixReleaseDate: &fix, FixStatus: FixBlockedByConstraint}, 10 * day, 20 * day},
+		{"blocked/open", Interval{VulStartDate: &start, FixReleaseDate: &fix, FixStatus: FixBlockedByConstraint}, 10 * day, 0},
+		{"blocked/fix_before_start", Interval{VulStartDate: &start, VulEndDate: &end, FixReleaseDate: &beforeStart, FixStatus: FixBlockedByConstraint}, 0, 30 * day},
+		{"admitted_not_picked", Interval{VulStartDate: &start, VulEndDate: &end, FixReleaseDate: &fix, FixStatus: FixAdmittedNotPicked}, 10 * day, 0},
+	}
+	for _, c := range cases {
+		noFix, blocked := c.interval.SplitAtFix()
+		if noFix != c.wantNoFix || blocked != c.wantBlocked {
+			t.Errorf("%s: SplitAtFix() = (%s, %s), want (%s, %s)", c.name, noFix, blocked, c.wantNoFix, c.wantBlocked)
+		}
+	}
+}
+
+func TestSplitAtDisclosure(t *testing.T) {
+	start := mustTime(t, "2020-01-01 00:00:00")
+	end := mustTime(t, "2020-01-31 00:00:00")
+	day := 24 * time.Hour
+	duration := func(d time.Duration) *time.Duration { return &d }
+
+	cases := []struct {
+		name      string
+		interval  Interval
+		disclosed string
+		want      DisclosureSplit
+	}{
+		{"disclosed_during", Interval{VulStartDate: &start, VulEndDate: &end}, "2020-01-11 00:00:00",
+			DisclosureSplit{PreDisclosureDuration: 10 * day, PostDisclosureDuration: duration(20 * day), DisclosureToEndDuration: duration(20 * day)}},
+		{"disclosed_before", Interval{VulStartDate: &start, VulEndDate: &end}, "2019-12-01 00:00:00",
+			DisclosureSplit{PostDisclosureDuration: duration(30 * day), DisclosureToEndDuration: duration(61 * day)}},
+		{"disclosed_after", Interval{VulStartDate: &start, VulEndDate: &end}, "2020-03-01 00:00:00",
+			DisclosureSplit{PreDisclosureDuration: 30 * day, PostDisclosureDuration: duration(0)}},
+		{"disclosed_at_end", Interval{VulStartDate: &start, VulEndDate: &end}, "2020-01-31 00:00:00",
+			DisclosureSplit{PreDisclosureDuration: 30 * day, PostDisclosureDuration: duration(0)}},
+		{"open/disclosed_after_start", Interval{VulStartDate: &start}, "2020-01-11 00:00:00",
+			DisclosureSplit{PreDisclosureDuration: 10 * day}},
+		// 公開後の期間の終わりが分からないので、公開後の時間は0ではなくnilになる
+		{"open/disclosed_before_start", Interval{VulStartDate: &start}, "2019-12-01 00:00:00",
+			DisclosureSplit{PostDisclosureDuration: nil, DisclosureToEndDuration: nil}},
+	}
+	for _, c := range cases {
+		if got := c.interval.SplitAtDisclosure(mustTime(t, c.disclosed)); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: SplitAtDisclosure() = %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package exposure
+
+import (
+	"analyzer/datasource"
+	"analyzer/models"
+	"context"
+	"reflect"
+	"testing"
+)
+
+// YAMLのフィクスチャファイルをMemoryRepositoryで読み込み、データベースを使うときと同じ流れで影響期間を求める
+func TestAnalyzeWithFixtureFile(t *testing.T) {
+	ctx := context.Background()
+	repository, err := datasource.NewMemoryRepositoryFromFile("testdata/upstream_fix.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vulPackage, err := repository.GetPackageById(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vulPackage.Name != "lodash" || vulPackage.SourceRank != 20 {
+		t.Fatalf("got project %+v", vulPackage)
+	}
+
+	vulPackageReleaseLogs, err := repository.GetVulPackageVersionsById(ctx, "1", models.Npm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Ecosystem: models.Npm, DependencyKinds: []models.DependencyKind{models.DependencyKindRuntime}}
+	got := make(map[string][]intervalSummary)
+	err = repository.FetchAffectedPackagesWithVersions(ctx, models.Npm, "1", func(affectedPackageId string, releaseLogs []models.ReleaseLog) error {
+		results, err := Analyze(releaseLogs, vulPackageReleaseLogs, "<1.0.1", opts)
+		if err != nil {
+			return err
+		}
+		if len(results) != 0 {
+			got[affectedPackageId] = summarize(results)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tool は開発時の依存なので解析しない
+	want := map[string][]intervalSummary{
+		"2": {{
+			Start: "2018-02-01 00:00:00", End: "2018-06-01 00:00:00", EndCause: EndCauseUpstreamFix,
+			StartRequirement: "^1.0.0", StartVersion: "1.0.0", PackageVersion: "1.0.0", EndVersion: "1.0.0",
+			FixVersion: "1.0.1", FixAdmitted: true, FixStatus: FixAutoPicked,
+			PreEndRequirement: "^1.0.0", EndRequirement: "^1.0.0", DependencyKind: models.DependencyKindRuntime,
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+}
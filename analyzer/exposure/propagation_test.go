@@ -0,0 +1,125 @@
+package exposure
+
+import (
+	"analyzer/datasource"
+	"analyzer/models"
+	"analyzer/sv"
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// lodash(1) に依存する app(2) の影響期間から派生制約を作り、app に依存するパッケージを派生制約で解析する.
+// app は 1.0.0〜1.1.0 と 2.0.0〜2.1.0 の2つの期間で影響を受けるので、派生制約は2つの範囲になる
+var propagationFixture = datasource.Fixture{
+	Projects: []models.Package{
+		{Id: "1", Platform: "NPM", Name: "lodash"},
+		{Id: "2", Platform: "NPM", Name: "app"},
+		{Id: "3", Platform: "NPM", Name: "top"},
+		{Id: "4", Platform: "NPM", Name: "other"},
+		{Id: "5", Platform: "NPM", Name: "mid"},
+	},
+	Versions: map[models.EcosystemType][]datasource.FixtureVersion{
+		models.Npm: {
+			{Id: "10", ProjectId: "1", ProjectName: "lodash", Number: "1.0.0", PublishedTimestamp: "2018-01-01 00:00:00"},
+			{Id: "11", ProjectId: "1", ProjectName: "lodash", Number: "1.3.0", PublishedTimestamp: "2019-01-01 00:00:00"},
+			{Id: "12", ProjectId: "1", ProjectName: "lodash", Number: "1.4.0", PublishedTimestamp: "2019-03-01 00:00:00"},
+			{Id: "13", ProjectId: "1", ProjectName: "lodash", Number: "1.4.1", PublishedTimestamp: "2019-09-01 00:00:00"},
+			{Id: "20", ProjectId: "2", ProjectName: "app", Number: "1.0.0", PublishedTimestamp: "2018-02-01 00:00:00"},
+			{Id: "21", ProjectId: "2", ProjectName: "app", Number: "1.1.0", PublishedTimestamp: "2018-06-01 00:00:00"},
+			{Id: "22", ProjectId: "2", ProjectName: "app", Number: "2.0.0", PublishedTimestamp: "2019-02-01 00:00:00"},
+			{Id: "23", ProjectId: "2", ProjectName: "app", Number: "2.1.0", PublishedTimestamp: "2019-05-01 00:00:00"},
+			{Id: "24", ProjectId: "2", ProjectName: "app", Number: "2.2.0", PublishedTimestamp: "2019-10-01 00:00:00"},
+			{Id: "30", ProjectId: "3", ProjectName: "top", Number: "0.1.0", PublishedTimestamp: "2019-04-01 00:00:00"},
+			{Id: "40", ProjectId: "4", ProjectName: "other", Number: "0.1.0", PublishedTimestamp: "2019-11-01 00:00:00"},
+			{Id: "50", ProjectId: "5", ProjectName: "mid", Number: "0.1.0", PublishedTimestamp: "2019-04-01 00:00:00"},
+		},
+	},
+	Dependencies: map[models.EcosystemType][]datasource.FixtureDependency{
+		models.Npm: {
+			{ProjectId: "2", ProjectName: "app", VersionId: "20", DependencyProjectId: "1", DependencyRequirements: "^1.0.0"},
+			{ProjectId: "2", ProjectName: "app", VersionId: "21", DependencyProjectId: "1", DependencyRequirements: "^1.0.0"},
+			{ProjectId: "2", ProjectName: "app", VersionId: "22", DependencyProjectId: "1", DependencyRequirements: "^1.3.0"},
+			{ProjectId: "2", ProjectName: "app", VersionId: "23", DependencyProjectId: "1", DependencyRequirements: "^1.3.0"},
+			{ProjectId: "2", ProjectName: "app", VersionId: "24", DependencyProjectId: "1", DependencyRequirements: "^1.4.1"},
+			// app 1.1.0 に解決される
+			{ProjectId: "3", ProjectName: "top", VersionId: "30", DependencyProjectId: "2", DependencyRequirements: "^1.0.0"},
+			// app 2.2.0 に解決される
+			{ProjectId: "4", ProjectName: "other", VersionId: "40", DependencyProjectId: "2", DependencyRequirements: ">=2.2.0"},
+			// app 2.0.0 に解決される
+			{ProjectId: "5", ProjectName: "mid", VersionId: "50", DependencyProjectId: "2", DependencyRequirements: "~2.0.0"},
+		},
+	},
+}
+
+func TestDerivedConstraintPropagation(t *testing.T) {
+	ctx := context.Background()
+	repository := datasource.NewMemoryRepository(propagationFixture)
+
+	// affectedPackages は vulPackageId に依存するパッケージのうち、vulConstraint の影響を受けたもののidと影響期間を返す
+	affectedPackages := func(vulPackageId string, vulConstraint string) map[string][]Interval {
+		vulPackageReleaseLogs, err := repository.GetVulPackageVersionsById(ctx, vulPackageId, models.Npm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		affected := make(map[string][]Interval)
+		err = repository.FetchAffectedPackagesWithVersions(ctx, models.Npm, vulPackageId, func(affectedPackageId string, releaseLogs []models.ReleaseLog) error {
+			results, err := Analyze(releaseLogs, vulPackageReleaseLogs, vulConstraint, Options{Ecosystem: models.Npm})
+			if err != nil {
+				return err
+			}
+			if len(results) != 0 {
+				affected[affectedPackageId] = results
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return affected
+	}
+
+	direct := affectedPackages("1", "<1.3.0 || >=1.4.0 <1.4.1")
+	if len(direct) != 1 || len(direct["2"]) != 2 {
+		t.Fatalf("app should be affected in 2 intervals: %+v", direct)
+	}
+	derived := DerivedConstraint(models.Npm, direct["2"])
+	if want := ">=1.0.0 <=1.1.0 || >=2.0.0 <=2.1.0"; derived != want {
+		t.Fatalf("DerivedConstraint = %q, want %q", derived, want)
+	}
+
+	transitive := affectedPackages("2", derived)
+	got := make([]string, 0, len(transitive))
+	for id := range transitive {
+		got = append(got, id)
+	}
+	sort.Strings(got)
+	if want := []string{"3", "5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("affected dependents of app = %v, want %v", got, want)
+	}
+	if v := transitive["3"][0].VulStartVersion.String(); v != "1.1.0" {
+		t.Errorf("top resolves app %s, want 1.1.0", v)
+	}
+	if v := transitive["5"][0].VulStartVersion.String(); v != "2.0.0" {
+		t.Errorf("mid resolves app %s, want 2.0.0", v)
+	}
+}
+
+func TestDerivedConstraintMergesOverlappingIntervals(t *testing.T) {
+	versions := func(start string, end string) Interval {
+		s, err := sv.NewVersion(models.Npm, start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := sv.NewVersion(models.Npm, end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Interval{PackageStartVersion: s, VulEndVersion: e}
+	}
+	intervals := []Interval{versions("1.0.0", "1.2.0"), versions("1.1.0", "1.3.0"), versions("2.0.0", "2.0.0")}
+	if got, want := DerivedConstraint(models.Npm, intervals), ">=1.0.0 <=1.3.0 || =2.0.0"; got != want {
+		t.Errorf("DerivedConstraint = %q, want %q", got, want)
+	}
+}
@@ -4,13 +4,16 @@ go 1.19
 
 require (
 	github.com/Masterminds/semver/v3 v3.2.0
+	github.com/cheggaaa/pb/v3 v3.1.0
 	github.com/go-sql-driver/mysql v1.7.0
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/neo4j/neo4j-go-driver/v5 v5.5.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/VividCortex/ewma v1.1.1 // indirect
-	github.com/cheggaaa/pb/v3 v3.1.0 // indirect
 	github.com/fatih/color v1.10.0 // indirect
 	github.com/mattn/go-colorable v0.1.8 // indirect
 	github.com/mattn/go-isatty v0.0.12 // indirect
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"analyzer/datasource"
+	"analyzer/models"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// Libraries.ioのCSVダンプから、MySQLを使わずに解析できるSQLiteのファイルを作る
+// go run ./importer -ecosystems npm,cargo -out lib.sqlite libraries-1.6.0-2020-01-12.tar.gz
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := handler(ctx); err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Println("中断しました")
+			stop()
+			os.Exit(1)
+		}
+		panic(err)
+	}
+}
+
+func handler(ctx context.Context) error {
+	var ecosystemsFlag string
+	var outputFile string
+	flag.StringVar(&ecosystemsFlag, "ecosystems", "cargo,npm,packagist,rubygems", "読み込むエコシステム(カンマ区切り)")
+	flag.StringVar(&outputFile, "out", "lib.sqlite", "作成するSQLiteのファイル")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		return fmt.Errorf("Libraries.ioのCSVダンプ(展開したディレクトリかtar.gz)を指定してください")
+	}
+	input := flag.Arg(0)
+
+	ecosystems := make([]models.EcosystemType, 0)
+	for _, e := range strings.Split(ecosystemsFlag, ",") {
+		ecosystem := models.EcosystemType(strings.TrimSpace(e))
+		if !ecosystem.IsValid() {
+			return fmt.Errorf("got unknown ecosystem type. ecosystem: '%s'", ecosystem)
+		}
+		ecosystems = append(ecosystems, ecosystem)
+	}
+
+	if _, err := os.Stat(outputFile); err == nil {
+		return fmt.Errorf("%s は既に存在します", outputFile)
+	}
+
+	db, err := sql.Open(string(datasource.SQLite), outputFile)
+	if err != nil {
+		return err
+	}
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(db)
+
+	// 途中で失敗したら作り直すので、書き込みの安全性より速度を優先する
+	if _, err := db.ExecContext(ctx, "PRAGMA journal_mode=OFF; PRAGMA synchronous=OFF"); err != nil {
+		return err
+	}
+
+	log.Printf("%s から %s を読み込みます", input, ecosystemsFlag)
+	if err := datasource.Import(ctx, db, datasource.SQLite, input, ecosystems); err != nil {
+		// 中断したときは作りかけのファイルを残さない
+		if ctx.Err() != nil {
+			if closeErr := db.Close(); closeErr != nil {
+				log.Printf("エラーが発生しました. error: %s", closeErr)
+			}
+			if removeErr := os.Remove(outputFile); removeErr != nil {
+				log.Printf("エラーが発生しました. error: %s", removeErr)
+			}
+		}
+		return err
+	}
+	log.Printf("%s を作成しました", outputFile)
+	return nil
+}
@@ -0,0 +1,172 @@
+package librariesio
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// File はLibraries.ioのCSVダンプのファイルの種類
+type File string
+
+const (
+	Projects                     File = "projects"
+	ProjectsWithRepositoryFields File = "projects_with_repository_fields"
+	Versions                     File = "versions"
+	Dependencies                 File = "dependencies"
+)
+
+// 全てのファイルで2列目がプラットフォーム
+const platformColumn = 1
+
+// fileOf はCSVダンプのファイル名(例: dependencies-1.6.0-2020-01-12.csv)からファイルの種類を求める.
+// repositories などの解析に使わないファイルは false を返す
+func fileOf(path string) (File, bool) {
+	name := filepath.Base(path)
+	if !strings.HasSuffix(name, ".csv") {
+		return "", false
+	}
+	for _, f := range []File{Projects, ProjectsWithRepositoryFields, Versions, Dependencies} {
+		if strings.HasPrefix(name, string(f)+"-") {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+// Walk はLibraries.ioのCSVダンプを1行ずつ読み、プラットフォームがplatformsに含まれる行だけをfnに渡す.
+// pathには展開したディレクトリか、配布されているtar.gzのアーカイブを指定する.
+// recordはfnの呼び出しの間だけ有効で、次の行の読み込みで上書きされる
+func Walk(path string, platforms []string, fn func(file File, record []string) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return walkDir(path, platforms, fn)
+	}
+	return walkArchive(path, platforms, fn)
+}
+
+func walkDir(dir string, platforms []string, fn func(file File, record []string) error) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		file, ok := fileOf(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if err := walkFile(filepath.Join(dir, entry.Name()), file, platforms, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkFile(path string, file File, platforms []string, fn func(file File, record []string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(f)
+
+	log.Printf("%s を読み込みます", path)
+	return readCSV(f, file, platforms, fn)
+}
+
+func walkArchive(path string, platforms []string, fn func(file File, record []string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(f)
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		file, ok := fileOf(header.Name)
+		if !ok || header.Typeflag != tar.TypeReg {
+			continue
+		}
+		log.Printf("%s を読み込みます", header.Name)
+		if err := readCSV(tr, file, platforms, fn); err != nil {
+			return err
+		}
+	}
+}
+
+func readCSV(r io.Reader, file File, platforms []string, fn func(file File, record []string) error) error {
+	isTarget := make(map[string]bool, len(platforms))
+	for _, p := range platforms {
+		isTarget[p] = true
+	}
+
+	cr := csv.NewReader(r)
+	cr.LazyQuotes = true
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	// ヘッダー
+	header, err := cr.Read()
+	if err != nil {
+		return err
+	}
+	columnCount := len(header)
+
+	row := 0
+	for {
+		record, err := cr.Read()
+		row++
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); ok {
+				log.Printf("読み込めない行をスキップしました. file: %s, row: %d, error: %s", file, row, err)
+				continue
+			}
+			return err
+		}
+		if len(record) != columnCount {
+			log.Printf("列の数が合わない行をスキップしました. file: %s, row: %d", file, row)
+			continue
+		}
+		if !isTarget[record[platformColumn]] {
+			continue
+		}
+
+		if err := fn(file, record); err != nil {
+			return fmt.Errorf("file: %s, row: %d: %w", file, row, err)
+		}
+	}
+}
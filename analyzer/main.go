@@ -1,44 +1,104 @@
 package main
 
 import (
+	"analyzer/cmd"
+	"analyzer/config"
 	"analyzer/datasource"
+	"analyzer/exposure"
 	"analyzer/models"
-	"analyzer/sv"
-	"database/sql"
+	"context"
 	"encoding/csv"
+	"errors"
+	"flag"
 	"fmt"
-	semver "github.com/Masterminds/semver/v3"
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 	"log"
 	"os"
+	"os/signal"
 	"strconv"
-	"time"
+	"syscall"
 )
 
 func main() {
-	if err := handler(); err != nil {
+	// Ctrl-Cで中断したときも、それまでの結果は書き出してから終了する
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := handler(ctx); err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Println("中断しました")
+			stop()
+			os.Exit(1)
+		}
 		panic(err)
 	}
 }
 
-type VulPackage struct {
-	PackageId     string
-	PackageName   string
-	VulConstraint string
-	Deps          int64
-}
+func handler(ctx context.Context) error {
+	var maxDepth int64
+	var asOfFlag string
+	var fixtureFile string
+	var sqliteFile string
+	var projectColumnsFlag string
+	var dependencyKindsFlag string
+	var cacheDir string
+	var cacheSnapshot string
+	var invalidateCache bool
+	flag.Int64Var(&maxDepth, "max-depth", 0, "推移的に辿る依存関係の深さの上限. 0なら直接依存のみ")
+	flag.StringVar(&asOfFlag, "as-of", models.SnapshotDate, "解析時点. これより後のリリースは無視し、続いている影響期間はこの時点で打ち切る")
+	flag.StringVar(&fixtureFile, "fixture", "", "MySQLの代わりにデータを読み込むJSONかYAMLのフィクスチャファイル")
+	flag.StringVar(&sqliteFile, "sqlite", "", "MySQLの代わりにデータを読み込む、importerで作ったSQLiteのファイル")
+	flag.StringVar(&projectColumnsFlag, "project-columns", "", "出力の末尾に追加する、影響を受けたパッケージのprojectsテーブルの列(カンマ区切り). 例: licenses,repository_url,dependent_projects_count,stars")
+	flag.StringVar(&dependencyKindsFlag, "dependency-kinds", "", "解析する依存関係の種類(runtime, dev, build, peer, optional のカンマ区切り). 空なら全ての種類")
+	flag.StringVar(&cacheDir, "cache-dir", "", "取得したリリース履歴をキャッシュするディレクトリ. 空ならキャッシュしない")
+	flag.StringVar(&cacheSnapshot, "cache-snapshot", models.SnapshotDate, "キャッシュのキーにするデータセットの版. データセットを入れ替えたら変える")
+	flag.BoolVar(&invalidateCache, "invalidate-cache", false, "解析の前に -cache-snapshot のキャッシュを消す")
+	dbFlags := config.RegisterDatabaseFlags(flag.CommandLine)
+	flag.Parse()
+
+	dbConfig, err := dbFlags.Load()
+	if err != nil {
+		return err
+	}
 
-func handler() error {
-	args := os.Args
-	vulPackgeInputFile := args[1]
-	outputFile := args[2]
-	ecosystemType := models.EcosystemType(args[3])
+	projectColumns, err := cmd.ParseProjectColumns(projectColumnsFlag)
+	if err != nil {
+		return err
+	}
+	dependencyKinds, err := models.ParseDependencyKinds(dependencyKindsFlag)
+	if err != nil {
+		return err
+	}
 
-	db, err := sql.Open("mysql", "root@(localhost:3306)/lib")
+	asOf, err := exposure.ParseAsOf(asOfFlag)
 	if err != nil {
 		return err
 	}
 
+	args := flag.Args()
+	vulPackgeInputFile := args[0]
+	outputFile := args[1]
+	ecosystemType := models.EcosystemType(args[2])
+
+	repository, err := openRepository(ctx, fixtureFile, sqliteFile, dbConfig, ecosystemType)
+	if err != nil {
+		return err
+	}
+	if cacheDir != "" {
+		cachedRepository, err := datasource.NewCachedRepository(repository, cacheDir, cacheSnapshot)
+		if err != nil {
+			return err
+		}
+		if invalidateCache {
+			if err := cachedRepository.Invalidate(); err != nil {
+				return err
+			}
+		}
+		repository = cachedRepository
+	}
+
 	// 脆弱性のリスト
 	file, err := os.Open(vulPackgeInputFile)
 	if err != nil {
@@ -57,26 +117,44 @@ func handler() error {
 		return err
 	}
 
-	vulPackages := make([]VulPackage, 0)
+	// 脆弱性パッケージの名前をまとめてproject_idに解決する
+	packageNames := make([]string, 0, len(rows))
+	for _, row := range rows {
+		packageNames = append(packageNames, row[1])
+	}
+	projectIds, failures, err := datasource.NewPackageResolver(repository).Resolve(ctx, ecosystemType, packageNames)
+	if err != nil {
+		return err
+	}
+	for _, failure := range failures {
+		log.Printf("パッケージ名を解決できませんでした. name: %s, error: %s", failure.Name, failure.Err)
+	}
+
+	vulPackages := make([]cmd.VulPackage, 0)
 	for i := len(rows) - 1; i >= 0; i-- {
-		projectId, err := datasource.GetPackageIdByName(db, ecosystemType, rows[i][1])
-		if err != nil {
-			log.Printf("エラーが発生しました. error: %s", err)
+		projectId, ok := projectIds[rows[i][1]]
+		if !ok {
 			continue
 		}
-		vulPackages = append(vulPackages, VulPackage{
+		vulPackages = append(vulPackages, cmd.VulPackage{
 			PackageId:     projectId,
 			PackageName:   rows[i][1],
 			VulConstraint: rows[i][2],
 			Deps:          0,
+			Path:          []string{projectId},
+			PublishedAt:   cmd.ParsePublishedAt(rows[i]),
+			Advisory:      i,
 		})
 	}
+	// 推移的に辿ったパッケージの、脆弱性・パッケージ・派生制約の組
+	propagated := make(map[string]bool)
 
 	vulPackagesOutputFile, err := os.Create("vul_packages_npm.csv")
 	if err != nil {
 		return err
 	}
 	vulPackagesOutputFileWriter := csv.NewWriter(vulPackagesOutputFile)
+	defer vulPackagesOutputFileWriter.Flush()
 	vulPackagesOutputFileWriter.Write([]string{
 		"vulPackageId",
 		"vulPakageName",
@@ -89,100 +167,101 @@ func handler() error {
 		return err
 	}
 	w := csv.NewWriter(affectedPackagesOutputFile)
-	if err := w.Write([]string{
-		"project_id",
-		"vul_project_id",
-		"vul_start_datetime",
-		"vul_end_datetime",
-		"vul_start_timestamp",
-		"vul_end_timestamp",
-		"compliantType",
-		"vul_start_dependency_compliant",
-		"vul_start_version",
-		"vul_deps",
-		// 脆弱性パッケージが(このパッケージも含めて)影響を与えたパッケージの総数
-		"vul_total_count",
-		"source_rank",
-	}); err != nil {
+	defer w.Flush()
+	if err := w.Write(cmd.AffectedPackagesHeaderWith(projectColumns)); err != nil {
 		return err
 	}
 
 	for len(vulPackages) != 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		affectedVulCount := 0
 
-		vulPakageName := vulPackages[0].PackageName
-		vulPackageId := vulPackages[0].PackageId
-		vulPackageDeps := vulPackages[0].Deps
-		vulConstraint := vulPackages[0].VulConstraint
+		vulPackage := vulPackages[0]
+		vulPakageName := vulPackage.PackageName
+		vulPackageId := vulPackage.PackageId
+		vulPackageDeps := vulPackage.Deps
+		vulConstraint := vulPackage.VulConstraint
+		vulPath := vulPackage.Path
 		vulPackages = vulPackages[1:]
 
 		// 深さ制限
-		if vulPackageDeps > 0 {
+		if vulPackageDeps > maxDepth {
 			continue
 		}
 
-		// vulPackageに依存しているパッケージを全て取得
-		packages, err := datasource.FetchAffectedPackagesWithVersions(db, ecosystemType, vulPackageId)
-		if err != nil {
-			return err
-		}
-		log.Printf("脆弱性を持ったパッケージ(%s)に依存しているパッケージが %d 個見つかりました", vulPackageId, len(packages))
-
 		// 脆弱性パッケージのリリース履歴を取得する
-		vulPackageReleaseLogs, err := datasource.GetVulPackageVersionsById(db, vulPackageId, ecosystemType)
+		vulPackageReleaseLogs, err := repository.GetVulPackageVersionsById(ctx, vulPackageId, ecosystemType)
 		if err != nil {
 			return err
 		}
 
-		i := 0
-		for affectedPackageId, releaseLogs := range packages {
-			i++
-			log.Printf("未解析脆弱パッケージ残り: %d 個の %d/%d   now: %s (%s), projectId:%s, releaseLogの数: %d 見つかった脆弱性の数: %d", len(vulPackages), i, len(packages), vulPakageName, vulConstraint, affectedPackageId, len(releaseLogs)+len(vulPackageReleaseLogs), affectedVulCount)
-			results, err := analyzeVulnerabilityDuration(db, affectedPackageId, vulPackageId, vulConstraint, ecosystemType, mergeTwoReleaseLogs(releaseLogs, vulPackageReleaseLogs))
+		// vulPackageに依存しているパッケージを1つずつ解析する.
+		// 依存しているパッケージを読んでいる間は接続を1つ使い続けるので、影響を受けたパッケージの情報は読み終わってから取得する
+		affectedPackageCount := 0
+		affectedResults := make([]affectedResult, 0)
+		err = repository.FetchAffectedPackagesWithVersions(ctx, ecosystemType, vulPackageId, func(affectedPackageId string, releaseLogs []models.ReleaseLog) error {
+			affectedPackageCount++
+			log.Printf("未解析脆弱パッケージ残り: %d 個の %d 個目   now: %s (%s), projectId:%s, releaseLogの数: %d 見つかった脆弱性の数: %d", len(vulPackages), affectedPackageCount, vulPakageName, vulConstraint, affectedPackageId, len(releaseLogs)+len(vulPackageReleaseLogs), affectedVulCount)
+			results, err := exposure.Analyze(releaseLogs, vulPackageReleaseLogs, vulConstraint, exposure.Options{AsOf: asOf, DependencyKinds: dependencyKinds, Ecosystem: ecosystemType})
 			if err != nil {
 				log.Printf("エラーが発生しました. error: %s, vulConstraint: %s", err, vulConstraint)
-				continue
+				return nil
+			}
+			if len(results) != 0 {
+				affectedVulCount += len(results)
+				affectedResults = append(affectedResults, affectedResult{
+					packageId:   affectedPackageId,
+					packageName: releaseLogs[0].ProjectName,
+					intervals:   results,
+				})
 			}
-			affectedPackage, err := datasource.GetPackageById(db, affectedPackageId)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, affected := range affectedResults {
+			affectedPackageId := affected.packageId
+			results := affected.intervals
+			affectedPackage, err := repository.GetPackageById(ctx, affectedPackageId)
 			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
 				log.Printf("エラーが発生しました. error: %s", err)
 				continue
 			}
 			for _, r := range results {
-				affectedVulCount++
-				var endDate *time.Time
-				if r.VulEndDate != nil {
-					endDate = r.VulEndDate
-				} else {
-					t := time.Now()
-					endDate = &t
-				}
-				if err := w.Write([]string{
-					affectedPackageId,
-					vulPackageId,
-					r.VulStartDate.String(),
-					endDate.String(),
-					strconv.FormatInt(r.VulStartDate.Unix(), 10),
-					strconv.FormatInt(endDate.Unix(), 10),
-					strconv.FormatInt(int64(r.CompliantType), 10),
-					r.VulStartDependencyRequirement,
-					r.VulStartVersion.String(),
-					strconv.FormatInt(vulPackageDeps, 10),
-					strconv.FormatInt(int64(len(results)), 10),
-					strconv.FormatInt(affectedPackage.SourceRank, 10),
-				}); err != nil {
+				if err := w.Write(cmd.AffectedPackageRecord(vulPackage, r, len(results), affectedPackage, projectColumns)); err != nil {
 					return err
 				}
-				//fmt.Printf("package %s: %s〜%s\n", p.ProjectId, r.VulStartDate.String(), endDate)
+			}
 
-				// 推移的な依存関係は後考える
-				//vulPackages = append(vulPackages, VulPackage{
-				//	PackageId:     r.PackageId,
-				//	VulConstraint: fmt.Sprintf("%s - %s", r.VulStartVersion, r.VulEndVersion),
-				//	Deps:          vulPackageDeps + 1,
-				//})
+			// 影響を受けていたバージョンを持つパッケージを、新たな脆弱性パッケージとして推移的に辿る.
+			// 同じ脆弱性から同じ派生制約で辿り着いたパッケージは、経路が違っても一度だけ解析する
+			if vulPackageDeps < maxDepth && !containsPackageId(vulPath, affectedPackageId) {
+				derivedConstraint := exposure.DerivedConstraint(ecosystemType, results)
+				key := fmt.Sprintf("%d\x00%s\x00%s", vulPackage.Advisory, affectedPackageId, derivedConstraint)
+				if !propagated[key] {
+					propagated[key] = true
+					path := make([]string, len(vulPath), len(vulPath)+1)
+					copy(path, vulPath)
+					vulPackages = append(vulPackages, cmd.VulPackage{
+						PackageId:     affectedPackageId,
+						PackageName:   affected.packageName,
+						VulConstraint: derivedConstraint,
+						Deps:          vulPackageDeps + 1,
+						Path:          append(path, affectedPackageId),
+						PublishedAt:   vulPackage.PublishedAt,
+						Advisory:      vulPackage.Advisory,
+					})
+				}
 			}
 		}
+		log.Printf("脆弱性を持ったパッケージ(%s)に依存しているパッケージが %d 個見つかりました", vulPackageId, affectedPackageCount)
 		vulPackagesOutputFileWriter.Write([]string{
 			vulPackageId,
 			vulPakageName,
@@ -192,307 +271,50 @@ func handler() error {
 		w.Flush()
 		vulPackagesOutputFileWriter.Flush()
 	}
-	vulPackagesOutputFileWriter.Flush()
-	w.Flush()
 	return nil
 }
 
-type AnalyzeVulnerabilityDurationResult struct {
-	PackageId                     string
-	VulPackageId                  string
-	VulStartDate                  *time.Time
-	VulEndDate                    *time.Time
-	CompliantType                 models.CompliantType
-	VulStartDependencyRequirement string
-	VulStartVersion               *semver.Version
-	VulEndVersion                 *semver.Version
-}
-
-func mergeTwoReleaseLogs(a []models.ReleaseLog, b []models.ReleaseLog) []models.ReleaseLog {
-	i := 0
-	j := 0
-	newReleaseLogs := make([]models.ReleaseLog, len(a)+len(b))
-	for k := 0; k < len(a)+len(b); k++ {
-		if i < len(a) && j < len(b) && a[i].PublishedTimestamp < b[j].PublishedTimestamp {
-			newReleaseLogs[k] = a[i]
-			i++
-		} else if j < len(b) {
-			newReleaseLogs[k] = b[j]
-			j++
-		}
+// フィクスチャファイルかSQLiteのファイルが指定されていればそれを、どちらも指定されていなければ接続設定のデータベースをデータの取得元にする.
+// データベースを使う場合は、解析に必要なテーブルとインデックスが揃っているかを先に確認する
+func openRepository(ctx context.Context, fixtureFile string, sqliteFile string, dbConfig config.Database, ecosystemType models.EcosystemType) (datasource.Repository, error) {
+	if fixtureFile != "" {
+		return datasource.NewMemoryRepositoryFromFile(fixtureFile)
 	}
 
-	return newReleaseLogs
-}
-
-func analyzeVulnerabilityDuration(db *sql.DB, packageId string, vulPackageId string, vulConstraint string, ecosystemType models.EcosystemType, releaseLogs []models.ReleaseLog) ([]AnalyzeVulnerabilityDurationResult, error) {
-	//releaseLogs, err := datasource.FetchMergedTwoPackageReleasesWithSort(db, ecosystemType, packageId, vulPackageId)
-	//if err != nil {
-	//	return nil, err
-	//}
-
-	// 脆弱性の影響を受けていた期間を特定
-	// 変数: 脆弱性の始まりと終わりのバージョン
-	isAlreadyPublishedPackage := false
-	nowAffectedVulnerability := false
-	var affectedVulnerabilityStartDate *time.Time
-
-	// 脆弱性の影響を受け始めたときの情報
-	var vulStartConstraint string
-	var vulStartVersion *semver.Version
-
-	results := make([]AnalyzeVulnerabilityDurationResult, 0)
-	for i, releaseLog := range releaseLogs {
-		if releaseLog.PackageType == "package" {
-			// 依存元のパッケージ
-			isAffectedVulnerability, v, err := isAffectedVulnerabilityWithPackage(*releaseLog.DependencyRequirements, releaseLogs[0:i], vulConstraint)
-			if err != nil {
-				return nil, err
-			}
-			if isAffectedVulnerability {
-				if !nowAffectedVulnerability {
-					d, err := time.Parse("2006-01-02 15:04:05", releaseLog.PublishedTimestamp)
-					if err != nil {
-						return nil, err
-					}
-					affectedVulnerabilityStartDate = &d
-					nowAffectedVulnerability = true
-
-					vulStartConstraint = *releaseLog.DependencyRequirements
-					vulStartVersion = v
-
-					//fmt.Printf("脆弱性の影響を受けはじめた. 受けはじめの時刻: %s\n", affectedVulnerabilityStartDate.String())
-				}
-				// 継続して脆弱性の影響を受けている
-			} else {
-				if nowAffectedVulnerability {
-					affectedVulnerabilityEndDate, err := time.Parse("2006-01-02 15:04:05", releaseLogs[i].PublishedTimestamp)
-					if err != nil {
-						return nil, err
-					}
-					//fmt.Printf("脆弱性の影響を受け終わった. 受け終わりの時刻: %s\n", affectedVulnerabilityEndDate.String())
-
-					compliantType, err := sv.CheckCompliantSemVer(vulStartConstraint, vulStartVersion)
-					if err != nil {
-						return nil, err
-					}
-
-					// 脆弱性が存在していた最新バージョンを取得したいので、自分のリリースを入れる必要はない
-					vulEndVersion, err := findLatestPackageVersion(releaseLogs[0:i])
-					if err != nil {
-						return nil, err
-					}
-
-					results = append(results, AnalyzeVulnerabilityDurationResult{
-						PackageId:                     packageId,
-						VulPackageId:                  vulPackageId,
-						VulStartDate:                  affectedVulnerabilityStartDate,
-						VulEndDate:                    &affectedVulnerabilityEndDate,
-						CompliantType:                 compliantType,
-						VulStartDependencyRequirement: vulStartConstraint,
-						VulStartVersion:               vulStartVersion,
-						VulEndVersion:                 vulEndVersion,
-					})
-
-					// 状態を初期化
-					nowAffectedVulnerability = false
-					affectedVulnerabilityStartDate = nil
-					vulStartConstraint = ""
-					vulStartVersion = nil
-				}
-			}
-			isAlreadyPublishedPackage = true
-		} else if releaseLog.PackageType == "vul_package" {
-			// 依存先のパッケージ(脆弱性を発生させたパッケージ)
-			// beforeReleaseには自分のリリースも入れる必要がある
-			isAffectedVulnerability, v, err := isAffectedVulnerabilityWithVulPackage(isAlreadyPublishedPackage, releaseLogs[0:i+1], vulConstraint)
-			if err != nil {
-				return nil, err
-			}
-			if isAffectedVulnerability {
-				if !nowAffectedVulnerability {
-					d, err := time.Parse("2006-01-02 15:04:05", releaseLog.PublishedTimestamp)
-					if err != nil {
-						return nil, err
-					}
-					affectedVulnerabilityStartDate = &d
-					nowAffectedVulnerability = true
-					vulStartVersion = v
-
-					//fmt.Printf("脆弱性の影響を受けはじめた. 受けはじめの時刻: %s\n", affectedVulnerabilityStartDate.String())
-				}
-				// 継続して脆弱性の影響を受けている
-			} else {
-				if nowAffectedVulnerability {
-					affectedVulnerabilityEndDate, err := time.Parse("2006-01-02 15:04:05", releaseLogs[i].PublishedTimestamp)
-					if err != nil {
-						return nil, err
-					}
-					//fmt.Printf("脆弱性の影響を受け終わった!! 受け終わりの時刻: %s\n", affectedVulnerabilityEndDate.String())
-
-					compliantType, err := sv.CheckCompliantSemVer(vulStartConstraint, vulStartVersion)
-					if err != nil {
-						return nil, err
-					}
-
-					// 脆弱性が存在していた最新バージョンを取得したいので、自分のリリースを入れる必要はない
-					vulEndVersion, err := findLatestPackageVersion(releaseLogs[0:i])
-					if err != nil {
-						return nil, err
-					}
-
-					results = append(results, AnalyzeVulnerabilityDurationResult{
-						PackageId:                     packageId,
-						VulPackageId:                  vulPackageId,
-						VulStartDate:                  affectedVulnerabilityStartDate,
-						VulEndDate:                    &affectedVulnerabilityEndDate,
-						CompliantType:                 compliantType,
-						VulStartDependencyRequirement: vulStartConstraint,
-						VulStartVersion:               vulStartVersion,
-						VulEndVersion:                 vulEndVersion,
-					})
-
-					// 状態を初期化
-					nowAffectedVulnerability = false
-					affectedVulnerabilityStartDate = nil
-					vulStartConstraint = ""
-					vulStartVersion = nil
-				}
-			}
-		} else {
-			return nil, fmt.Errorf("got unknown type of package. type: %s", releaseLog.PackageType)
-		}
-	}
-
-	if nowAffectedVulnerability {
-		// 脆弱性が存在していた最新バージョンを取得したいので、自分のリリースを入れる必要はない
-		vulEndVersion, err := findLatestPackageVersion(releaseLogs)
+	var repository *datasource.SQLRepository
+	if sqliteFile != "" {
+		var err error
+		repository, err = datasource.OpenSQLiteRepository(sqliteFile)
 		if err != nil {
 			return nil, err
 		}
-
-		compliantType, err := sv.CheckCompliantSemVer(vulStartConstraint, vulStartVersion)
+	} else {
+		var err error
+		repository, err = dbConfig.OpenRepository(ctx)
 		if err != nil {
 			return nil, err
 		}
-
-		results = append(results, AnalyzeVulnerabilityDurationResult{
-			PackageId:                     packageId,
-			VulPackageId:                  vulPackageId,
-			VulStartDate:                  affectedVulnerabilityStartDate,
-			VulEndDate:                    nil,
-			CompliantType:                 compliantType,
-			VulStartDependencyRequirement: vulStartConstraint,
-			VulStartVersion:               vulStartVersion,
-			VulEndVersion:                 vulEndVersion,
-		})
-	}
-
-	return results, nil
-}
-
-func findLatestPackageVersion(beforeReleases []models.ReleaseLog) (*semver.Version, error) {
-	for i := len(beforeReleases) - 1; i >= 0; i-- {
-		if beforeReleases[i].PackageType == "package" {
-			v, err := semver.NewVersion(beforeReleases[i].VersionNumber)
-			if err != nil {
-				return nil, err
-			}
-			return v, nil
-		}
 	}
-	return nil, fmt.Errorf("最新の依存関係制約が見つかりませんでした")
-}
 
-func findLatestPackageDependencyRequirements(beforeReleases []models.ReleaseLog) (string, error) {
-	for i := len(beforeReleases) - 1; i >= 0; i-- {
-		if beforeReleases[i].PackageType == "package" {
-			return *beforeReleases[i].DependencyRequirements, nil
-		}
+	if err := repository.CheckSchema(ctx, ecosystemType); err != nil {
+		return nil, fmt.Errorf("%w. go run ./schema create や load で準備してください", err)
 	}
-	return "", fmt.Errorf("最新の依存関係制約が見つかりませんでした")
+	return repository, nil
 }
 
-func isAffectedVulnerabilityWithVulPackage(isAlreadyPublishedPackage bool, beforeReleases []models.ReleaseLog, vulConstraint string) (bool, *semver.Version, error) {
-	if !isAlreadyPublishedPackage {
-		return false, nil, nil
-	}
-
-	requirements, err := findLatestPackageDependencyRequirements(beforeReleases)
-	if err != nil {
-		return false, nil, err
-	}
-
-	for i := len(beforeReleases) - 1; i >= 0; i-- {
-		if beforeReleases[i].PackageType != "vul_package" {
-			continue
-		}
-
-		v, err := semver.NewVersion(beforeReleases[i].VersionNumber)
-		if err != nil {
-			return false, nil, err
-		}
-
-		// 制約を満たすかどうか
-		c, err := semver.NewConstraint(requirements)
-		if err != nil {
-			return false, nil, err
-		}
-		isValidVersionWithRequirements := c.Check(v)
-		// 制約を満たしていなければ脆弱かどうかを調べる必要がないのでcontinue
-		if !isValidVersionWithRequirements {
-			continue
-		}
-
-		// 脆弱性影響を受けているかどうか
-		c, err = semver.NewConstraint(vulConstraint)
-		if err != nil {
-			return false, nil, err
-		}
-		version, err := semver.NewVersion(beforeReleases[i].VersionNumber)
-		if err != nil {
-			return false, nil, err
-		}
-
-		return c.Check(v), version, nil
-	}
-	return false, nil, fmt.Errorf("制約を満たすバージョンが見つかりませんでした. 制約: '%s'", requirements)
+// affectedResult は脆弱性パッケージに依存していて、影響を受けていた期間があったパッケージ
+type affectedResult struct {
+	packageId   string
+	packageName string
+	intervals   []exposure.Interval
 }
 
-func isAffectedVulnerabilityWithPackage(requirements string, beforeReleases []models.ReleaseLog, vulConstraint string) (bool, *semver.Version, error) {
-	for i := len(beforeReleases) - 1; i >= 0; i-- {
-		// 最新から順に制約を満たすかどうか確認する
-		if beforeReleases[i].PackageType == "vul_package" {
-			v, err := semver.NewVersion(beforeReleases[i].VersionNumber)
-			if err != nil {
-				return false, nil, err
-			}
-
-			// 制約を満たすかどうか
-			c, err := semver.NewConstraint(requirements)
-			if err != nil {
-				return false, nil, err
-			}
-			isValidVersionWithRequirements := c.Check(v)
-			// 制約を満たしていなければ脆弱かどうかを調べる必要がないのでcontinue
-			if !isValidVersionWithRequirements {
-				continue
-			}
-
-			// 脆弱性影響を受けているかどうか
-			c, err = semver.NewConstraint(vulConstraint)
-			if err != nil {
-				return false, nil, err
-			}
-
-			vulStartVersion, err := semver.NewVersion(beforeReleases[i].VersionNumber)
-			if err != nil {
-				return false, nil, err
-			}
-
-			return c.Check(v), vulStartVersion, nil
+// 依存経路が循環しないように、経路に既に含まれているパッケージかどうかを調べる
+func containsPackageId(path []string, packageId string) bool {
+	for _, id := range path {
+		if id == packageId {
+			return true
 		}
 	}
-	// 一度もヒットしなければ、エラー
-	return false, nil, fmt.Errorf("制約を満たすバージョンが見つかりませんでした. 制約: '%s'", requirements)
+	return false
 }
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"fmt"
+	"strings"
+)
+
 type ReleaseLog struct {
 	ProjectId              string  `json:"project_id"`
 	ProjectName            string  `json:"project_name"`
@@ -8,6 +13,70 @@ type ReleaseLog struct {
 	DependencyRequirements *string `json:"dependency_requirements"`
 	PublishedTimestamp     string  `json:"published_timestamp"`
 	PackageType            string  `json:"type"`
+	// 依存関係の種類. PackageTypeが "package" のときだけ値がある
+	DependencyKind DependencyKind `json:"dependency_kind"`
+}
+
+// DependencyKind は依存関係の種類. Libraries.ioのデータセットの dependency_kind と optional_dependency を
+// エコシステムによらない種類にまとめたもの
+type DependencyKind string
+
+const (
+	DependencyKindRuntime  DependencyKind = "runtime"
+	DependencyKindDev      DependencyKind = "dev"
+	DependencyKindBuild    DependencyKind = "build"
+	DependencyKindPeer     DependencyKind = "peer"
+	DependencyKindOptional DependencyKind = "optional"
+)
+
+// DependencyKinds は全ての依存関係の種類. 実際に配布物に含まれやすい順に並べている
+var DependencyKinds = []DependencyKind{DependencyKindRuntime, DependencyKindPeer, DependencyKindOptional, DependencyKindBuild, DependencyKindDev}
+
+// NormalizeDependencyKind はLibraries.ioのデータセットの dependency_kind と optional_dependency から依存関係の種類を求める.
+// cargoの normal やnpmの runtime などは runtime に、Development や dev は dev にまとめる.
+// 分からない種類は、影響を見落とさないように runtime として扱う
+func NormalizeDependencyKind(kind string, optional string) DependencyKind {
+	dependencyKind := DependencyKindRuntime
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "development", "dev", "test":
+		dependencyKind = DependencyKindDev
+	case "build":
+		dependencyKind = DependencyKindBuild
+	case "peer":
+		dependencyKind = DependencyKindPeer
+	case "optional":
+		dependencyKind = DependencyKindOptional
+	}
+
+	switch strings.ToLower(strings.TrimSpace(optional)) {
+	case "true", "t", "1":
+		if dependencyKind == DependencyKindRuntime {
+			dependencyKind = DependencyKindOptional
+		}
+	}
+	return dependencyKind
+}
+
+// ParseDependencyKinds はカンマ区切りの依存関係の種類を検証して一覧にする. 空文字列ならnilを返す
+func ParseDependencyKinds(s string) ([]DependencyKind, error) {
+	var kinds []DependencyKind
+	for _, k := range strings.Split(s, ",") {
+		kind := DependencyKind(strings.TrimSpace(k))
+		if kind == "" {
+			continue
+		}
+		isValid := false
+		for _, dependencyKind := range DependencyKinds {
+			if kind == dependencyKind {
+				isValid = true
+			}
+		}
+		if !isValid {
+			return nil, fmt.Errorf("got unknown dependency kind. kind: '%s'", kind)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
 }
 
 type CompliantType int64
@@ -22,6 +91,9 @@ const (
 	ZeroVersionRestrictive
 )
 
+// Libraries.ioのデータセット(libraries-1.6.0-2020-01-12)のスナップショット日
+const SnapshotDate = "2020-01-12"
+
 type EcosystemType string
 
 const (
@@ -31,6 +103,76 @@ const (
 	RubyGems  EcosystemType = "rubygems"
 )
 
+// EcosystemTypes は解析対象の全てのエコシステム
+var EcosystemTypes = []EcosystemType{Cargo, Npm, Packagist, RubyGems}
+
+// Libraries.ioのデータセットでのプラットフォーム名
+var platforms = map[EcosystemType]string{
+	Cargo:     "Cargo",
+	Npm:       "NPM",
+	Packagist: "Packagist",
+	RubyGems:  "Rubygems",
+}
+
+// Platform はLibraries.ioのデータセットでのプラットフォーム名を返す
+func (e EcosystemType) Platform() string {
+	return platforms[e]
+}
+
+// EcosystemTypeFromPlatform はLibraries.ioのデータセットでのプラットフォーム名からエコシステムを求める
+func EcosystemTypeFromPlatform(platform string) (EcosystemType, bool) {
+	for ecosystemType, p := range platforms {
+		if p == platform {
+			return ecosystemType, true
+		}
+	}
+	return "", false
+}
+
+// IsValid は解析対象のエコシステムかどうかを返す
+func (e EcosystemType) IsValid() bool {
+	for _, ecosystemType := range EcosystemTypes {
+		if e == ecosystemType {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizePackageName はパッケージ名を、レジストリと同じ規則で同じパッケージかどうか比較できる形にする.
+//
+//   - packagist: vendor/name は大文字と小文字を区別しない
+//   - cargo: crates.ioは大文字と小文字、'-' と '_' を区別しない
+//   - npm: 大文字を含む古いパッケージや、@scope/name と name は別のパッケージなのでそのまま比較する
+//   - rubygems: 大文字と小文字を区別するのでそのまま比較する
+func (e EcosystemType) NormalizePackageName(name string) string {
+	name = strings.TrimSpace(name)
+	switch e {
+	case Packagist:
+		return strings.ToLower(name)
+	case Cargo:
+		return strings.ReplaceAll(strings.ToLower(name), "-", "_")
+	default:
+		return name
+	}
+}
+
+// Package はLibraries.ioの projects テーブルの1行と、リポジトリのスター数. 値が無い列はゼロ値になる
 type Package struct {
-	SourceRank int64
+	Id                            string `json:"id" yaml:"id"`
+	Platform                      string `json:"platform" yaml:"platform"`
+	Name                          string `json:"name" yaml:"name"`
+	CreatedTimestamp              string `json:"created_timestamp" yaml:"created_timestamp"`
+	HomepageUrl                   string `json:"homepage_url" yaml:"homepage_url"`
+	Licenses                      string `json:"licenses" yaml:"licenses"`
+	RepositoryUrl                 string `json:"repository_url" yaml:"repository_url"`
+	VersionsCount                 int64  `json:"versions_count" yaml:"versions_count"`
+	SourceRank                    int64  `json:"source_rank" yaml:"source_rank"`
+	LatestReleasePublishTimestamp string `json:"latest_release_publish_timestamp" yaml:"latest_release_publish_timestamp"`
+	LatestReleaseNumber           string `json:"latest_release_number" yaml:"latest_release_number"`
+	DependentProjectsCount        int64  `json:"dependent_projects_count" yaml:"dependent_projects_count"`
+	Language                      string `json:"language" yaml:"language"`
+	Status                        string `json:"status" yaml:"status"`
+	DependentRepositoriesCount    int64  `json:"dependent_repositories_count" yaml:"dependent_repositories_count"`
+	Stars                         int64  `json:"stars" yaml:"stars"`
 }
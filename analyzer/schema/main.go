@@ -0,0 +1,123 @@
+package main
+
+import (
+	"analyzer/config"
+	"analyzer/datasource"
+	"analyzer/models"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+const usage = `解析に使うテーブルとインデックスを管理する
+
+  go run ./schema [flags] ddl              テーブルとインデックスを作るSQLを出力する
+  go run ./schema [flags] create           テーブルとインデックスを作る. 既にあるものはそのままにする
+  go run ./schema [flags] load <dump>      Libraries.ioのCSVダンプ(展開したディレクトリかtar.gz)を読み込む
+  go run ./schema [flags] check            解析に必要なテーブル・インデックス・データが揃っているか確認する
+
+flags:
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := handler(ctx); err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Println("中断しました")
+			stop()
+			os.Exit(1)
+		}
+		panic(err)
+	}
+}
+
+func handler(ctx context.Context) error {
+	var ecosystemsFlag string
+	var sqliteFile string
+	flag.StringVar(&ecosystemsFlag, "ecosystems", "cargo,npm,packagist,rubygems", "対象のエコシステム(カンマ区切り)")
+	flag.StringVar(&sqliteFile, "sqlite", "", "接続設定のデータベースの代わりに使うSQLiteのファイル")
+	dbFlags := config.RegisterDatabaseFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ecosystems := make([]models.EcosystemType, 0)
+	for _, e := range strings.Split(ecosystemsFlag, ",") {
+		ecosystem := models.EcosystemType(strings.TrimSpace(e))
+		if !ecosystem.IsValid() {
+			return fmt.Errorf("got unknown ecosystem type. ecosystem: '%s'", ecosystem)
+		}
+		ecosystems = append(ecosystems, ecosystem)
+	}
+
+	dbConfig, err := dbFlags.Load()
+	if err != nil {
+		return err
+	}
+	if sqliteFile != "" {
+		dbConfig.Driver = string(datasource.SQLite)
+		dbConfig.DSN = sqliteFile
+	}
+	dialect := dbConfig.Dialect()
+
+	if flag.Arg(0) == "ddl" {
+		for _, table := range datasource.SchemaTables(ecosystems) {
+			fmt.Printf("%s;\n\n", table.CreateTableStatement(dialect))
+			for _, statement := range table.CreateIndexStatements() {
+				fmt.Printf("%s;\n", statement)
+			}
+			fmt.Println()
+		}
+		return nil
+	}
+
+	db, err := dbConfig.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer func(db *sql.DB) {
+		err := db.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(db)
+
+	switch flag.Arg(0) {
+	case "create":
+		return datasource.CreateSchema(ctx, db, dialect, ecosystems)
+	case "load":
+		if flag.NArg() != 2 {
+			return fmt.Errorf("Libraries.ioのCSVダンプ(展開したディレクトリかtar.gz)を指定してください")
+		}
+		return datasource.Import(ctx, db, dialect, flag.Arg(1), ecosystems)
+	case "check":
+		if err := datasource.CheckSchema(ctx, db, dialect, ecosystems); err != nil {
+			return err
+		}
+		log.Printf("%s の解析に必要なスキーマは揃っています", ecosystemsFlag)
+		return nil
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package sv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	cargoOperatorSpacing = regexp.MustCompile(`(>=|<=|>|<|=|~|\^)\s+`)
+	cargoPartialPattern  = regexp.MustCompile(`^([0-9]+|[xX*])(?:\.([0-9]+|[xX*])(?:\.([0-9]+|[xX*])(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*)?)?)?$`)
+	cargoOperators       = []string{">=", "<=", ">", "<", "=", "~", "^"}
+)
+
+// CargoConstraint はCargo(semver crate)と同じ規則で解釈した依存関係制約.
+// 演算子の無いバージョン(1.2.3, 0.4)は ^ と同じく左端の0でない部分を変えない更新を許し、
+// 1.2.* のようなワイルドカードは省略された部分だけを変える更新を許す
+type CargoConstraint struct {
+	requirement string
+	comparators []comparator
+}
+
+// NewCargoConstraint はCargo.tomlの依存関係のバージョン指定を解釈する.
+// 比較はカンマで区切るが、脆弱性のバージョン範囲(>=0 <0.14.10)のように空白で区切ったものも受け付ける
+func NewCargoConstraint(requirement string) (*CargoConstraint, error) {
+	fields := strings.FieldsFunc(cargoOperatorSpacing.ReplaceAllString(strings.TrimSpace(requirement), "$1"), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(fields) == 0 {
+		// バージョンを指定していない依存関係は * と同じ
+		return &CargoConstraint{requirement: requirement, comparators: []comparator{anyComparator}}, nil
+	}
+
+	comparators := make([]comparator, 0, len(fields))
+	for _, field := range fields {
+		op := ""
+		for _, o := range cargoOperators {
+			if strings.HasPrefix(field, o) {
+				op = o
+				break
+			}
+		}
+		version := field[len(op):]
+		if version == "" {
+			return nil, fmt.Errorf("Cargoのバージョン指定として解釈できません. requirement: '%s'", requirement)
+		}
+		p, err := parsePartial(cargoPartialPattern, version)
+		if err != nil {
+			return nil, fmt.Errorf("Cargoのバージョン指定として解釈できません. requirement: '%s': %w", requirement, err)
+		}
+
+		switch {
+		case op == "" && p.parts < 3 && strings.ContainsAny(version, "*xX"):
+			// ワイルドカード
+			comparators = append(comparators, primitiveRange("", p)...)
+		case op == "" || op == "^":
+			comparators = append(comparators, caretRange(p)...)
+		case op == "~":
+			comparators = append(comparators, tildeRange(p)...)
+		default:
+			comparators = append(comparators, primitiveRange(op, p)...)
+		}
+	}
+	return &CargoConstraint{requirement: requirement, comparators: comparators}, nil
+}
+
+func (c *CargoConstraint) Check(v Version) bool {
+	s, ok := v.(semVersion)
+	return ok && checkComparatorSet(c.comparators, s.Version)
+}
+
+func (c *CargoConstraint) Resolvable() bool {
+	return true
+}
+
+func (c *CargoConstraint) String() string {
+	return c.requirement
+}
+
+func (c *CargoConstraint) toIntervals() ([]interval, error) {
+	return comparatorSetIntervals(c.comparators), nil
+}
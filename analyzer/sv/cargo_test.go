@@ -0,0 +1,125 @@
+package sv
+
+import (
+	"analyzer/models"
+	"testing"
+)
+
+// cargoCheckCases はsemver crateのVersionReq::matchesと同じ結果になるはずの、バージョンが制約を満たすかどうか.
+// 脆弱性のバージョン範囲と同じく、NewConstraintを通して || で区切った制約も確かめる
+var cargoCheckCases = []struct {
+	name       string
+	version    string
+	constraint string
+	want       bool
+}{
+	{"bare/caret", "1.9.0", "1.2.3", true},
+	{"bare/lower", "1.2.2", "1.2.3", false},
+	{"bare/major", "2.0.0", "1.2.3", false},
+	{"bare/minor only", "0.4.9", "0.4", true},
+	{"bare/minor only upper", "0.5.0", "0.4", false},
+	{"bare/minor only lower", "0.3.9", "0.4", false},
+	{"bare/zero major", "0.4.5", "0.4.1", true},
+	{"bare/zero major upper", "0.5.0", "0.4.1", false},
+	{"bare/major only", "1.9.9", "1", true},
+	{"bare/major only upper", "2.0.0", "1", false},
+	{"bare/zero only", "0.9.9", "0", true},
+	{"bare/zero only upper", "1.0.0", "0", false},
+	{"zero minor/pinned", "0.0.3", "0.0.3", true},
+	{"zero minor/next patch", "0.0.4", "0.0.3", false},
+	{"zero minor/caret", "0.0.4", "^0.0.3", false},
+	{"zero minor/caret partial", "0.0.9", "^0.0", true},
+	{"zero minor/caret partial upper", "0.1.0", "^0.0", false},
+	{"zero minor/tilde", "0.0.9", "~0.0.3", true},
+	{"wildcard/any", "5.0.0", "*", true},
+	{"wildcard/empty", "5.0.0", "", true},
+	{"wildcard/major", "1.9.0", "1.*", true},
+	{"wildcard/major upper", "2.0.0", "1.*", false},
+	{"wildcard/minor", "1.2.9", "1.2.*", true},
+	{"wildcard/minor upper", "1.3.0", "1.2.*", false},
+	{"wildcard/x", "1.2.9", "1.2.x", true},
+	{"wildcard/zero", "0.9.0", "0.*", true},
+	{"wildcard/zero upper", "1.0.0", "0.*", false},
+	{"tilde", "1.2.9", "~1.2.3", true},
+	{"tilde/upper", "1.3.0", "~1.2.3", false},
+	{"tilde/major only", "1.9.0", "~1", true},
+	{"comma", "1.4.9", ">=1.2.0, <1.5.0", true},
+	{"comma/upper", "1.5.0", ">=1.2.0, <1.5.0", false},
+	{"exact", "1.2.4", "= 1.2.3", false},
+	{"space separated", "0.14.9", ">=0 <0.14.10", true},
+	{"space separated/upper", "0.14.10", ">= 0 < 0.14.10", false},
+	{"or", "0.6.1", "<0.5.4 || >=0.6.0, <0.6.2", true},
+	{"or/gap", "0.5.5", "<0.5.4 || >=0.6.0, <0.6.2", false},
+	{"prerelease/not opted in", "1.3.0-alpha", "^1.2.3", false},
+	{"prerelease/same tuple", "1.2.3-alpha.2", ">=1.2.3-alpha.1", true},
+}
+
+// cargoCompliantCases は、制約が満たされるバージョンに対してCargoの規則(^が既定)に沿っているかどうか
+var cargoCompliantCases = []struct {
+	constraint string
+	version    string
+	want       models.CompliantType
+}{
+	{"1.2.3", "1.2.3", models.Compliant},
+	{"^1.2", "1.2.3", models.Compliant},
+	{"=1.2.3", "1.2.3", models.Restrictive},
+	{"~1.2.3", "1.2.3", models.Restrictive},
+	{">=1.2.3", "1.2.3", models.Permissive},
+	{"*", "1.0.0", models.Permissive},
+	{"0.4.1", "0.4.1", models.ZeroVersionCompliant},
+	{"=0.4.1", "0.4.1", models.ZeroVersionRestrictive},
+	{">=0.4.1", "0.4.1", models.ZeroVersionPermissive},
+	{"0.0.3", "0.0.3", models.ZeroVersionCompliant},
+	{"~0.0.3", "0.0.3", models.ZeroVersionPermissive},
+}
+
+func TestCargoConstraintCheck(t *testing.T) {
+	for _, c := range cargoCheckCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			v, err := NewVersion(models.Cargo, c.version)
+			if err != nil {
+				t.Fatalf("version %q: %s", c.version, err)
+			}
+			constraint, err := NewConstraint(models.Cargo, c.constraint)
+			if err != nil {
+				t.Fatalf("constraint %q: %s", c.constraint, err)
+			}
+			if got := constraint.Check(v); got != c.want {
+				t.Errorf("Check(%q, %q) = %v, want %v", c.version, c.constraint, got, c.want)
+			}
+		})
+	}
+
+	for _, requirement := range []string{"^", ">=", "1.2.3.4", "abc", "<1.0.0 || 1.2.3.4"} {
+		if _, err := NewConstraint(models.Cargo, requirement); err == nil {
+			t.Errorf("parse(%q) should fail", requirement)
+		}
+	}
+}
+
+func TestCheckCompliantCargo(t *testing.T) {
+	for _, c := range cargoCompliantCases {
+		v, err := NewVersion(models.Cargo, c.version)
+		if err != nil {
+			t.Errorf("version %q: %s", c.version, err)
+			continue
+		}
+		got, err := CheckCompliantSemVer(models.Cargo, c.constraint, v)
+		if err != nil {
+			t.Errorf("CheckCompliantSemVer(%q, %q): %s", c.constraint, c.version, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("CheckCompliantSemVer(%q, %q) = %d, want %d", c.constraint, c.version, got, c.want)
+		}
+	}
+
+	v, err := NewVersion(models.Cargo, "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CheckCompliantSemVer(models.Cargo, "^2", v); err == nil {
+		t.Errorf("CheckCompliantSemVer should fail for a version outside the constraint")
+	}
+}
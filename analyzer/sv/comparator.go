@@ -0,0 +1,224 @@
+package sv
+
+import (
+	"fmt"
+	semver "github.com/Masterminds/semver/v3"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// comparator はバージョンとの比較1つ. opが空なら全てのバージョンを満たす
+type comparator struct {
+	op      string
+	version *semver.Version
+}
+
+var anyComparator = comparator{}
+
+// noneComparator はどのバージョンも満たさない比較
+var noneComparator = comparator{op: "<", version: semver.New(0, 0, 0, "0", "")}
+
+func (c comparator) check(v *semver.Version) bool {
+	switch c.op {
+	case "":
+		return true
+	case "=":
+		return v.Equal(c.version)
+	case "<":
+		return v.LessThan(c.version)
+	case "<=":
+		return !v.GreaterThan(c.version)
+	case ">":
+		return v.GreaterThan(c.version)
+	default:
+		return !v.LessThan(c.version)
+	}
+}
+
+// checkComparatorSet は全ての比較を満たすかどうかを返す.
+// node-semverやCargoと同じく、プレリリースのバージョンは、同じ major.minor.patch のプレリリースを比較に含む範囲でのみ満たす
+func checkComparatorSet(set []comparator, v *semver.Version) bool {
+	for _, c := range set {
+		if !c.check(v) {
+			return false
+		}
+	}
+	if v.Prerelease() == "" {
+		return true
+	}
+	for _, c := range set {
+		if c.op == "" || c.version.Prerelease() == "" {
+			continue
+		}
+		if c.version.Major() == v.Major() && c.version.Minor() == v.Minor() && c.version.Patch() == v.Patch() {
+			return true
+		}
+	}
+	return false
+}
+
+// partial は 1, 1.2, 1.x, 1.2.3-beta.1 のような一部を省略できるバージョン
+type partial struct {
+	major, minor, patch uint64
+	// 数値で指定された部分の数(0〜3). x, X, * とそれ以降の部分は数えない
+	parts      int
+	prerelease string
+}
+
+// parsePartial はpatternでバージョンを解釈する. patternのグループは順に major, minor, patch, プレリリース
+func parsePartial(pattern *regexp.Regexp, s string) (partial, error) {
+	m := pattern.FindStringSubmatch(s)
+	if m == nil {
+		if strings.TrimSpace(s) == "" {
+			// 演算子だけ、または空は * と同じ
+			return partial{}, nil
+		}
+		return partial{}, fmt.Errorf("バージョンとして解釈できません. version: '%s'", s)
+	}
+
+	var p partial
+	numbers := []*uint64{&p.major, &p.minor, &p.patch}
+	for i, n := range m[1:4] {
+		if n == "" || n == "x" || n == "X" || n == "*" {
+			break
+		}
+		value, err := strconv.ParseUint(n, 10, 64)
+		if err != nil {
+			return partial{}, err
+		}
+		*numbers[i] = value
+		p.parts++
+	}
+	if p.parts == 3 {
+		p.prerelease = m[4]
+	}
+	return p, nil
+}
+
+func (p partial) version() *semver.Version {
+	return semver.New(p.major, p.minor, p.patch, p.prerelease, "")
+}
+
+// primitiveRange は <, <=, >, >=, = と省略できるバージョンの比較. 省略された部分はx-rangeとして扱う
+func primitiveRange(op string, p partial) []comparator {
+	if p.parts == 3 {
+		if op == "" {
+			op = "="
+		}
+		return []comparator{{op: op, version: p.version()}}
+	}
+
+	if p.parts == 0 {
+		if op == "<" || op == ">" {
+			return []comparator{noneComparator}
+		}
+		return []comparator{anyComparator}
+	}
+
+	switch op {
+	case "", "=":
+		// 1 → >=1.0.0 <2.0.0-0, 1.2 → >=1.2.0 <1.3.0-0
+		return []comparator{
+			{op: ">=", version: semver.New(p.major, p.minor, 0, "", "")},
+			{op: "<", version: p.next()},
+		}
+	case ">":
+		return []comparator{{op: ">=", version: semver.New(p.next().Major(), p.next().Minor(), 0, "", "")}}
+	case "<=":
+		return []comparator{{op: "<", version: p.next()}}
+	case "<":
+		return []comparator{{op: "<", version: semver.New(p.major, p.minor, 0, "0", "")}}
+	default:
+		return []comparator{{op: ">=", version: semver.New(p.major, p.minor, 0, "", "")}}
+	}
+}
+
+// next は省略された部分の直前の数字を1つ上げたバージョンの、最も小さいプレリリース(1 → 2.0.0-0, 1.2 → 1.3.0-0)
+func (p partial) next() *semver.Version {
+	if p.parts == 1 {
+		return semver.New(p.major+1, 0, 0, "0", "")
+	}
+	return semver.New(p.major, p.minor+1, 0, "0", "")
+}
+
+// tildeRange はマイナーバージョンが指定されていればパッチの更新を、そうでなければマイナーの更新を許す
+func tildeRange(p partial) []comparator {
+	switch p.parts {
+	case 0:
+		return []comparator{anyComparator}
+	case 1, 2:
+		return primitiveRange("", p)
+	default:
+		return []comparator{
+			{op: ">=", version: p.version()},
+			{op: "<", version: semver.New(p.major, p.minor+1, 0, "0", "")},
+		}
+	}
+}
+
+// caretRange は左端の0でない部分を変えない更新を許す
+func caretRange(p partial) []comparator {
+	switch {
+	case p.parts == 0:
+		return []comparator{anyComparator}
+	case p.parts == 1:
+		return primitiveRange("", p)
+	case p.parts == 2:
+		if p.major == 0 {
+			return primitiveRange("", p)
+		}
+		return []comparator{
+			{op: ">=", version: semver.New(p.major, p.minor, 0, "", "")},
+			{op: "<", version: semver.New(p.major+1, 0, 0, "0", "")},
+		}
+	}
+
+	upper := semver.New(p.major+1, 0, 0, "0", "")
+	if p.major == 0 && p.minor == 0 {
+		upper = semver.New(0, 0, p.patch+1, "0", "")
+	} else if p.major == 0 {
+		upper = semver.New(0, p.minor+1, 0, "0", "")
+	}
+	return []comparator{
+		{op: ">=", version: p.version()},
+		{op: "<", version: upper},
+	}
+}
+
+// intervals は比較を満たすバージョンの区間を返す
+func (c comparator) intervals() []interval {
+	v := semVersion{c.version}
+	switch c.op {
+	case "":
+		return anyVersion()
+	case "=":
+		return exactly(v)
+	case "<":
+		if c.version.Equal(noneComparator.version) {
+			// 0.0.0-0 より小さいバージョンは無い
+			return nil
+		}
+		if c.version.Prerelease() == "0" {
+			// x-rangeや ^, ~ の上限の <2.0.0-0 は、プレリリースの規則により <2.0.0 と同じバージョンを満たす.
+			// 区間では <2.0.0 にして、>=2.0.0 から始まる区間と隙間なくつながるようにする
+			return lessThan(semVersion{semver.New(c.version.Major(), c.version.Minor(), c.version.Patch(), "", "")})
+		}
+		return lessThan(v)
+	case "<=":
+		return atMost(v)
+	case ">":
+		return greaterThan(v)
+	default:
+		return atLeast(v)
+	}
+}
+
+// comparatorSetIntervals は全ての比較を満たすバージョンの区間を返す. プレリリースの規則(checkComparatorSet)は扱わない
+func comparatorSetIntervals(set []comparator) []interval {
+	result := anyVersion()
+	for _, c := range set {
+		result = intersectIntervals(result, c.intervals())
+	}
+	return result
+}
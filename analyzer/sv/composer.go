@@ -0,0 +1,677 @@
+package sv
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Composer(composer/semver)のVersionParserと同じ規則でバージョンと制約を解釈する.
+// バージョンは 1.2.3.0-beta2 のような4つの数字とstabilityの形に正規化し、PHPのversion_compareで比べる
+
+const composerModifier = `[._-]?(?:(stable|beta|b|RC|alpha|a|patch|pl|p)((?:[.-]?\d+)*)?)?([.-]?dev)?`
+const composerVersion = `v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:\.(\d+))?` + composerModifier + `(?:\+[^\s]+)?`
+
+var (
+	composerClassicalPattern   = regexp.MustCompile(`(?i)^v?(\d{1,5})(\.\d+)?(\.\d+)?(\.\d+)?` + composerModifier + `$`)
+	composerDatePattern        = regexp.MustCompile(`(?i)^v?(\d{4}(?:[.:-]?\d{2}){1,6}(?:[.:-]?\d{1,3})?)` + composerModifier + `$`)
+	composerDevPattern         = regexp.MustCompile(`(?i)^(.*?)[.-]?dev$`)
+	composerBranchPattern      = regexp.MustCompile(`(?i)^v?(\d+)(\.(?:\d+|[xX*]))?(\.(?:\d+|[xX*]))?(\.(?:\d+|[xX*]))?$`)
+	composerAliasPattern       = regexp.MustCompile(`^([^,\s]+) +as +([^,\s]+)$`)
+	composerFlagPattern        = regexp.MustCompile(`(?i)^([^,\s]*?)@(stable|RC|beta|alpha|dev)$`)
+	composerVersionFlagPattern = regexp.MustCompile(`(?i)@(?:stable|RC|beta|alpha|dev)$`)
+	composerNonDigitPattern    = regexp.MustCompile(`\D`)
+	composerCommitRefPattern   = regexp.MustCompile(`#.+$`)
+	composerBuildPattern       = regexp.MustCompile(`^([^,\s+]+)\+[^\s]+$`)
+	composerRefPattern         = regexp.MustCompile(`(?i)^(dev-[^,\s@]+?|[^,\s@]+?\.x-dev)#.+$`)
+	composerStabilityPattern   = regexp.MustCompile(`(?i)` + composerModifier + `(?:\+.*)?$`)
+	composerSuffixPattern      = regexp.MustCompile(`(?i)-` + composerModifier + `$`)
+	composerMatchAllPattern    = regexp.MustCompile(`(?i)^(v)?[xX*](\.[xX*])*$`)
+	composerTildePattern       = regexp.MustCompile(`(?i)^~>?` + composerVersion + `$`)
+	composerCaretPattern       = regexp.MustCompile(`(?i)^\^` + composerVersion + `$`)
+	composerXRangePattern      = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:\.[xX*])+$`)
+	composerHyphenPattern      = regexp.MustCompile(`(?i)^(` + composerVersion + `) +- +(` + composerVersion + `)$`)
+	composerComparisonPattern  = regexp.MustCompile(`^(<>|!=|>=?|<=?|==?)?\s*(.*)$`)
+	composerOperatorPattern    = regexp.MustCompile(`^(<>|!=|>=?|<=?|==?)$`)
+	composerOrPattern          = regexp.MustCompile(`\s*\|\|?\s*`)
+	composerAndPattern         = regexp.MustCompile(`\s*,\s*|\s+`)
+	composerBranchNamePattern  = regexp.MustCompile(`^[0-9a-zA-Z-./]+$`)
+	composerDevBoundPattern    = regexp.MustCompile(`^[0-9.]+-dev$`)
+)
+
+// composerStability はパッケージのstability. 値が大きいほど不安定
+type composerStability int
+
+const (
+	composerStable composerStability = 0
+	composerRC     composerStability = 5
+	composerBeta   composerStability = 10
+	composerAlpha  composerStability = 15
+	composerDev    composerStability = 20
+)
+
+var composerStabilities = map[string]composerStability{
+	"stable": composerStable,
+	"rc":     composerRC,
+	"beta":   composerBeta,
+	"alpha":  composerAlpha,
+	"dev":    composerDev,
+}
+
+// ComposerVersion はComposerと同じ規則で解釈したPackagistのバージョン.
+// v1.2.3 のようなタグや、dev-master, 2.0.x-dev のようなブランチも扱う
+type ComposerVersion struct {
+	version    string
+	normalized string
+}
+
+func NewComposerVersion(version string) (*ComposerVersion, error) {
+	normalized, err := normalizeComposerVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	return &ComposerVersion{version: strings.TrimSpace(version), normalized: normalized}, nil
+}
+
+func (v *ComposerVersion) String() string {
+	return v.version
+}
+
+// Normalized は 1.2.3.0-beta2 や dev-master のような正規化したバージョンを返す
+func (v *ComposerVersion) Normalized() string {
+	return v.normalized
+}
+
+func (v *ComposerVersion) Compare(o Version) int {
+	return phpVersionCompare(v.normalized, o.(*ComposerVersion).normalized)
+}
+
+func (v *ComposerVersion) Major() uint64 {
+	return v.number(0)
+}
+
+func (v *ComposerVersion) Minor() uint64 {
+	return v.number(1)
+}
+
+func (v *ComposerVersion) Patch() uint64 {
+	return v.number(2)
+}
+
+// number は正規化したバージョンのi番目の数字. ブランチは0
+func (v *ComposerVersion) number(i int) uint64 {
+	if strings.HasPrefix(v.normalized, "dev-") {
+		return 0
+	}
+	numbers := strings.Split(strings.SplitN(v.normalized, "-", 2)[0], ".")
+	if i >= len(numbers) {
+		return 0
+	}
+	n, err := strconv.ParseUint(numbers[i], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// normalizeComposerVersion はVersionParser::normalizeと同じくバージョンを正規化する
+func normalizeComposerVersion(version string) (string, error) {
+	original := version
+	version = strings.TrimSpace(version)
+
+	if m := composerAliasPattern.FindStringSubmatch(version); m != nil {
+		version = m[1]
+	}
+	version = composerVersionFlagPattern.ReplaceAllString(version, "")
+	if version == "master" || version == "trunk" || version == "default" {
+		version = "dev-" + version
+	}
+	if strings.HasPrefix(strings.ToLower(version), "dev-") {
+		return "dev-" + version[4:], nil
+	}
+	if m := composerBuildPattern.FindStringSubmatch(version); m != nil {
+		version = m[1]
+	}
+
+	var m []string
+	index := 0
+	if m = composerClassicalPattern.FindStringSubmatch(version); m != nil {
+		version = m[1]
+		for _, part := range m[2:5] {
+			if part == "" {
+				part = ".0"
+			}
+			version += part
+		}
+		index = 5
+	} else if m = composerDatePattern.FindStringSubmatch(version); m != nil {
+		version = composerNonDigitPattern.ReplaceAllString(m[1], ".")
+		index = 2
+	}
+	if m != nil {
+		if m[index] != "" {
+			if m[index] == "stable" {
+				return version, nil
+			}
+			version += "-" + expandComposerStability(m[index]) + strings.TrimLeft(m[index+1], ".-")
+		}
+		if m[index+2] != "" {
+			version += "-dev"
+		}
+		return version, nil
+	}
+
+	// 1.x-dev のような数字のブランチ
+	if m := composerDevPattern.FindStringSubmatch(version); m != nil {
+		if normalized := normalizeComposerBranch(m[1]); !strings.HasPrefix(normalized, "dev-") {
+			return normalized, nil
+		}
+	}
+	return "", fmt.Errorf("Packagistのバージョンとして解釈できません. version: '%s'", original)
+}
+
+// normalizeComposerBranch はVersionParser::normalizeBranchと同じく、1.x のようなブランチ名を 1.9999999.9999999.9999999-dev にする.
+// 数字でないブランチ名は dev- を付ける
+func normalizeComposerBranch(name string) string {
+	name = strings.TrimSpace(name)
+	m := composerBranchPattern.FindStringSubmatch(name)
+	if m == nil {
+		return "dev-" + name
+	}
+	version := m[1]
+	for _, part := range m[2:5] {
+		if part == "" {
+			part = ".x"
+		}
+		version += strings.NewReplacer("*", "x", "X", "x").Replace(part)
+	}
+	return strings.ReplaceAll(version, "x", "9999999") + "-dev"
+}
+
+func expandComposerStability(stability string) string {
+	switch stability = strings.ToLower(stability); stability {
+	case "a":
+		return "alpha"
+	case "b":
+		return "beta"
+	case "p", "pl":
+		return "patch"
+	case "rc":
+		return "RC"
+	default:
+		return stability
+	}
+}
+
+// parseComposerStability はVersionParser::parseStabilityと同じくバージョンのstabilityを返す
+func parseComposerStability(version string) composerStability {
+	version = composerCommitRefPattern.ReplaceAllString(version, "")
+	if strings.HasPrefix(version, "dev-") || strings.HasSuffix(version, "-dev") {
+		return composerDev
+	}
+	m := composerStabilityPattern.FindStringSubmatch(strings.ToLower(version))
+	switch {
+	case m[3] != "":
+		return composerDev
+	case m[1] == "beta" || m[1] == "b":
+		return composerBeta
+	case m[1] == "alpha" || m[1] == "a":
+		return composerAlpha
+	case m[1] == "rc":
+		return composerRC
+	default:
+		return composerStable
+	}
+}
+
+// ComposerConstraint はComposerと同じ規則で解釈したPackagistの依存関係制約.
+// Composerのminimum-stabilityの既定値(stable)と同じく、制約が @dev のようなstabilityフラグや
+// 1.0.0-beta2 のような不安定なバージョンを書いていなければ、安定版でないバージョンは満たさない
+type ComposerConstraint struct {
+	constraint string
+	// || または | で区切ったそれぞれの、全て満たす必要がある比較. 比較が無ければ全てのバージョンを満たす
+	groups [][]composerComparison
+	// 制約が許す最も不安定なstability
+	stability  composerStability
+	resolvable bool
+}
+
+// composerComparison は正規化したバージョンとの比較1つ
+type composerComparison struct {
+	op      string
+	version string
+}
+
+// NewComposerConstraint はcomposer.jsonの依存関係制約を解釈する.
+// 同じパッケージのバージョンに合わせる self.version はレジストリのバージョンに解決できない制約にする
+func NewComposerConstraint(constraint string) (*ComposerConstraint, error) {
+	c := &ComposerConstraint{constraint: constraint, resolvable: true}
+	if strings.TrimSpace(constraint) == "self.version" {
+		c.resolvable = false
+		return c, nil
+	}
+
+	for _, or := range composerOrPattern.Split(strings.TrimSpace(constraint), -1) {
+		group := make([]composerComparison, 0)
+		for _, and := range splitComposerAnd(or) {
+			comparisons, stability, err := c.parse(and)
+			if err != nil {
+				return nil, fmt.Errorf("Packagistの依存関係制約として解釈できません. constraint: '%s': %w", constraint, err)
+			}
+			group = append(group, comparisons...)
+			if stability > c.stability {
+				c.stability = stability
+			}
+		}
+		c.groups = append(c.groups, group)
+	}
+	return c, nil
+}
+
+// splitComposerAnd はカンマか空白で区切られた比較を分ける. 演算子と値の間の空白、ハイフン範囲(1 - 2)、別名(as)は区切らない
+func splitComposerAnd(constraint string) []string {
+	tokens := composerAndPattern.Split(strings.TrimSpace(constraint), -1)
+	parts := make([]string, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		part := tokens[i]
+		for i+1 < len(tokens) && composerOperatorPattern.MatchString(part) {
+			i++
+			part += tokens[i]
+		}
+		for i+2 < len(tokens) && (tokens[i+1] == "-" || tokens[i+1] == "as") {
+			part += " " + tokens[i+1] + " " + tokens[i+2]
+			i += 2
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// parse はVersionParser::parseConstraintと同じく、比較1つを正規化したバージョンとの比較の列にする.
+// 制約に書かれたバージョンやstabilityフラグのstabilityも返す
+func (c *ComposerConstraint) parse(constraint string) ([]composerComparison, composerStability, error) {
+	if m := composerAliasPattern.FindStringSubmatch(constraint); m != nil {
+		constraint = m[1]
+	}
+	stabilityModifier := ""
+	stability := composerStable
+	if m := composerFlagPattern.FindStringSubmatch(constraint); m != nil {
+		constraint = m[1]
+		if constraint == "" {
+			constraint = "*"
+		}
+		if strings.ToLower(m[2]) != "stable" {
+			stabilityModifier = strings.ToLower(m[2])
+			stability = composerStabilities[stabilityModifier]
+		}
+	}
+	if m := composerRefPattern.FindStringSubmatch(constraint); m != nil {
+		constraint = m[1]
+	}
+	atLeast := func(version string) composerStability {
+		if s := parseComposerStability(version); s > stability {
+			return s
+		}
+		return stability
+	}
+
+	if m := composerMatchAllPattern.FindStringSubmatch(constraint); m != nil {
+		if m[1] != "" || m[2] != "" {
+			return []composerComparison{{">=", "0.0.0.0-dev"}}, stability, nil
+		}
+		return []composerComparison{}, stability, nil
+	}
+
+	if m := composerTildePattern.FindStringSubmatch(constraint); m != nil {
+		if strings.HasPrefix(constraint, "~>") {
+			return nil, 0, fmt.Errorf("~> は使えません. ~ を使ってください. constraint: '%s'", constraint)
+		}
+		// 指定された最後の数字より1つ上の位置を上げる
+		position := 1
+		for i := 4; i > 1; i-- {
+			if m[i] != "" {
+				position = i
+				break
+			}
+		}
+		low, err := normalizeComposerVersion(constraint[1:] + composerDevSuffix(m[5], m[7]))
+		if err != nil {
+			return nil, 0, err
+		}
+		high := manipulateComposerVersion(m[1:5], maxInt(1, position-1), 1) + "-dev"
+		return []composerComparison{{">=", low}, {"<", high}}, atLeast(constraint[1:]), nil
+	}
+
+	if m := composerCaretPattern.FindStringSubmatch(constraint); m != nil {
+		// 左端の0でない数字の位置を上げる
+		position := 3
+		if m[1] != "0" || m[2] == "" {
+			position = 1
+		} else if m[2] != "0" || m[3] == "" {
+			position = 2
+		}
+		low, err := normalizeComposerVersion(constraint[1:] + composerDevSuffix(m[5], m[7]))
+		if err != nil {
+			return nil, 0, err
+		}
+		high := manipulateComposerVersion(m[1:5], position, 1) + "-dev"
+		return []composerComparison{{">=", low}, {"<", high}}, atLeast(constraint[1:]), nil
+	}
+
+	if m := composerXRangePattern.FindStringSubmatch(constraint); m != nil {
+		position := 1
+		for i := 3; i > 1; i-- {
+			if m[i] != "" {
+				position = i
+				break
+			}
+		}
+		numbers := append(m[1:4], "")
+		low := manipulateComposerVersion(numbers, position, 0) + "-dev"
+		high := manipulateComposerVersion(numbers, position, 1) + "-dev"
+		if low == "0.0.0.0-dev" {
+			return []composerComparison{{"<", high}}, stability, nil
+		}
+		return []composerComparison{{">=", low}, {"<", high}}, stability, nil
+	}
+
+	if m := composerHyphenPattern.FindStringSubmatch(constraint); m != nil {
+		// m[1]からm[8]が下限、m[9]からm[16]が上限
+		low, err := normalizeComposerVersion(m[1])
+		if err != nil {
+			return nil, 0, err
+		}
+		high, err := normalizeComposerVersion(m[9])
+		if err != nil {
+			return nil, 0, err
+		}
+		comparisons := []composerComparison{{">=", low + composerDevSuffix(m[6], m[8])}}
+		if (m[11] != "" && m[12] != "") || m[14] != "" || m[16] != "" {
+			comparisons = append(comparisons, composerComparison{"<=", high})
+		} else {
+			position := 2
+			if m[11] == "" {
+				position = 1
+			}
+			comparisons = append(comparisons, composerComparison{"<", manipulateComposerVersion(m[10:14], position, 1) + "-dev"})
+		}
+		s := atLeast(m[1])
+		if to := parseComposerStability(m[9]); to > s {
+			s = to
+		}
+		return comparisons, s, nil
+	}
+
+	m := composerComparisonPattern.FindStringSubmatch(constraint)
+	version, err := normalizeComposerVersion(m[2])
+	if err != nil {
+		// foobar-dev は dev-foobar と同じ
+		if !strings.HasSuffix(m[2], "-dev") || !composerBranchNamePattern.MatchString(m[2]) {
+			return nil, 0, err
+		}
+		if version, err = normalizeComposerVersion("dev-" + strings.TrimSuffix(m[2], "-dev")); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	op := m[1]
+	switch op {
+	case "", "=":
+		op = "=="
+	case "<>":
+		op = "!="
+	}
+	if op != "==" && stabilityModifier != "" && parseComposerStability(version) == composerStable {
+		version += "-" + stabilityModifier
+	} else if (op == "<" || op == ">=") && !composerSuffixPattern.MatchString(strings.ToLower(m[2])) && !strings.HasPrefix(m[2], "dev-") {
+		version += "-dev"
+	}
+	return []composerComparison{{op, version}}, atLeast(m[2]), nil
+}
+
+// composerDevSuffix はstabilityが書かれていないバージョンの下限に付ける -dev を返す
+func composerDevSuffix(stability string, dev string) string {
+	if stability == "" && dev == "" {
+		return "-dev"
+	}
+	return ""
+}
+
+// manipulateComposerVersion はVersionParser::manipulateVersionStringと同じく、
+// position番目(1始まり)の数字にincrementを足し、それより後ろを0にした4つの数字のバージョンを返す
+func manipulateComposerVersion(numbers []string, position int, increment uint64) string {
+	parts := make([]string, 4)
+	for i := 0; i < 4; i++ {
+		switch {
+		case i+1 > position:
+			parts[i] = "0"
+		case i+1 == position && increment != 0:
+			n, _ := strconv.ParseUint(numbers[i], 10, 64)
+			parts[i] = strconv.FormatUint(n+increment, 10)
+		default:
+			parts[i] = numbers[i]
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func (c *ComposerConstraint) Check(v Version) bool {
+	cv, ok := v.(*ComposerVersion)
+	if !ok || !c.resolvable || parseComposerStability(cv.normalized) > c.stability {
+		return false
+	}
+	return c.matches(cv.normalized)
+}
+
+// matches はstabilityを考えずに、正規化したバージョンが制約を満たすかどうかを返す
+func (c *ComposerConstraint) matches(version string) bool {
+	for _, group := range c.groups {
+		matched := true
+		for _, comparison := range group {
+			if !comparison.matches(version) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *ComposerConstraint) Resolvable() bool {
+	return c.resolvable
+}
+
+func (c *ComposerConstraint) String() string {
+	return c.constraint
+}
+
+// toIntervals は比較を正規化したバージョンの区間にする. ブランチはバージョンの順序に並ばないので区間にできない
+func (c *ComposerConstraint) toIntervals() ([]interval, error) {
+	if !c.resolvable {
+		return nil, fmt.Errorf("レジストリのバージョンに解決されない制約はバージョン範囲にできません. constraint: '%s'", c.constraint)
+	}
+	result := make([]interval, 0)
+	for _, group := range c.groups {
+		intervals := anyVersion()
+		for _, comparison := range group {
+			if strings.HasPrefix(comparison.version, "dev-") {
+				return nil, fmt.Errorf("ブランチを指す制約はバージョン範囲にできません. constraint: '%s'", c.constraint)
+			}
+			intervals = intersectIntervals(intervals, comparison.intervals())
+		}
+		result = append(result, intervals...)
+	}
+	return normalizeIntervals(result), nil
+}
+
+// normalizedString はComposerのConstraintとMultiConstraintの__toStringと同じ形で、正規化した比較を返す
+func (c *ComposerConstraint) normalizedString() string {
+	groups := make([]string, 0, len(c.groups))
+	for _, group := range c.groups {
+		comparisons := make([]string, 0, len(group))
+		for _, comparison := range group {
+			comparisons = append(comparisons, comparison.op+" "+comparison.version)
+		}
+		switch len(comparisons) {
+		case 0:
+			groups = append(groups, "*")
+		case 1:
+			groups = append(groups, comparisons[0])
+		default:
+			groups = append(groups, "["+strings.Join(comparisons, " ")+"]")
+		}
+	}
+	if len(groups) == 1 {
+		return groups[0]
+	}
+	return "[" + strings.Join(groups, " || ") + "]"
+}
+
+// matches はConstraint::versionCompareと同じく比べる. ブランチ(dev-)は同じブランチとの == と != でのみ比べられる
+func (c composerComparison) matches(version string) bool {
+	isBranch := strings.HasPrefix(version, "dev-")
+	isConstraintBranch := strings.HasPrefix(c.version, "dev-")
+	if c.op == "!=" && (isBranch || isConstraintBranch) {
+		return version != c.version
+	}
+	if isBranch || isConstraintBranch {
+		return c.op == "==" && version == c.version
+	}
+
+	cmp := phpVersionCompare(version, c.version)
+	switch c.op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// intervals は比較を満たすバージョンの区間を返す.
+// < と >= の比較でComposerが付けた -dev は、書き戻した範囲を解釈したときに開発版を許さないように区間のバージョンの書き方から除く
+func (c composerComparison) intervals() []interval {
+	v := &ComposerVersion{version: c.version, normalized: c.version}
+	if (c.op == "<" || c.op == ">=") && composerDevBoundPattern.MatchString(c.version) {
+		v.version = strings.TrimSuffix(c.version, "-dev")
+	}
+	switch c.op {
+	case "==":
+		return exactly(v)
+	case "!=":
+		return notEqual(v)
+	case "<":
+		return lessThan(v)
+	case "<=":
+		return atMost(v)
+	case ">":
+		return greaterThan(v)
+	default:
+		return atLeast(v)
+	}
+}
+
+// phpVersionCompare はPHPのversion_compareと同じく比べる.
+// 数字と英字の境目と - _ + で区切り、英字は dev < alpha = a < beta = b < RC = rc < 数字 < pl = p の順、それ以外の英字は dev より小さい
+func phpVersionCompare(a string, b string) int {
+	pa := canonicalPHPVersion(a)
+	pb := canonicalPHPVersion(b)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if cmp := comparePHPVersionPart(pa[i], pb[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	// 残った部分が数字なら残ったほうが新しく、英字なら数字と比べる(1.0-beta < 1.0 < 1.0-pl1)
+	switch {
+	case len(pa) > len(pb):
+		if isPHPVersionNumber(pa[len(pb)]) {
+			return 1
+		}
+		return comparePHPVersionPart(pa[len(pb)], "#")
+	case len(pa) < len(pb):
+		if isPHPVersionNumber(pb[len(pa)]) {
+			return -1
+		}
+		return comparePHPVersionPart("#", pb[len(pa)])
+	}
+	return 0
+}
+
+func isPHPVersionNumber(part string) bool {
+	return part[0] >= '0' && part[0] <= '9'
+}
+
+func canonicalPHPVersion(version string) []string {
+	var b strings.Builder
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	isAlnum := func(c byte) bool { return isDigit(c) || (c|0x20 >= 'a' && c|0x20 <= 'z') }
+	for i := 0; i < len(version); i++ {
+		c := version[i]
+		switch {
+		case i > 0 && isAlnum(c) && isAlnum(version[i-1]) && isDigit(c) != isDigit(version[i-1]):
+			b.WriteByte('.')
+			b.WriteByte(c)
+		case isAlnum(c):
+			b.WriteByte(c)
+		default:
+			b.WriteByte('.')
+		}
+	}
+	parts := make([]string, 0)
+	for _, part := range strings.Split(b.String(), ".") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// comparePHPVersionPart は区切った部分1つを比べる. # は数字を表す
+func comparePHPVersionPart(a string, b string) int {
+	aDigit := isPHPVersionNumber(a)
+	bDigit := isPHPVersionNumber(b)
+	switch {
+	case aDigit && bDigit:
+		a = strings.TrimLeft(a, "0")
+		b = strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			return compareInt(len(a), len(b))
+		}
+		return strings.Compare(a, b)
+	case aDigit:
+		a = "#"
+	case bDigit:
+		b = "#"
+	}
+	return compareInt(phpSpecialVersionOrder(a), phpSpecialVersionOrder(b))
+}
+
+func phpSpecialVersionOrder(form string) int {
+	for _, special := range []struct {
+		name  string
+		order int
+	}{{"dev", 0}, {"alpha", 1}, {"a", 1}, {"beta", 2}, {"b", 2}, {"RC", 3}, {"rc", 3}, {"#", 4}, {"pl", 5}, {"p", 5}} {
+		if strings.HasPrefix(form, special.name) {
+			return special.order
+		}
+	}
+	return -6
+}
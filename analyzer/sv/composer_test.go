@@ -0,0 +1,411 @@
+package sv
+
+import (
+	"testing"
+)
+
+// Composer(composer/semver)のテストから移した、VersionParserとConstraintの期待値.
+// 正規化した制約はComposerのConstraintとMultiConstraintの__toStringと同じ形で書く
+
+var composerNormalizeCases = []struct {
+	name    string
+	version string
+	want    string
+}{
+	{"none", "1.0.0", "1.0.0.0"},
+	{"none/2", "1.2.3.4", "1.2.3.4"},
+	{"parses state", "1.0.0RC1dev", "1.0.0.0-RC1-dev"},
+	{"CI parsing", "1.0.0-rC15-dev", "1.0.0.0-RC15-dev"},
+	{"delimiters", "1.0.0.RC.15-dev", "1.0.0.0-RC15-dev"},
+	{"RC uppercase", "1.0.0-rc1", "1.0.0.0-RC1"},
+	{"patch replace", "1.0.0.pl3-dev", "1.0.0.0-patch3-dev"},
+	{"forces w.x.y.z", "1.0-dev", "1.0.0.0-dev"},
+	{"forces w.x.y.z/2", "0", "0.0.0.0"},
+	{"parses long", "10.4.13-beta", "10.4.13.0-beta"},
+	{"parses long/2", "10.4.13beta2", "10.4.13.0-beta2"},
+	{"parses long/semver", "10.4.13-beta.2", "10.4.13.0-beta2"},
+	{"parses long/semver2", "v1.13.11-beta.0", "1.13.11.0-beta0"},
+	{"parses long/semver3", "1.13.11.0-beta0", "1.13.11.0-beta0"},
+	{"expand shorthand", "10.4.13-b", "10.4.13.0-beta"},
+	{"expand shorthand/2", "10.4.13-b5", "10.4.13.0-beta5"},
+	{"strips leading v", "v1.0.0", "1.0.0.0"},
+	{"parses dates y-m as classical", "2010.01", "2010.01.0.0"},
+	{"parses dates w/ . as classical", "2010.01.02", "2010.01.02.0"},
+	{"parses dates y.m.Y as classical", "2010.1.555", "2010.1.555.0"},
+	{"parses dates y.m.Y/2 as classical", "2010.10.200", "2010.10.200.0"},
+	{"strips v/datetime", "v20100102", "20100102"},
+	{"parses dates w/ -", "2010-01-02", "2010.01.02"},
+	{"parses numbers", "2010-01-02.5", "2010.01.02.5"},
+	{"parses datetime", "20100102-203040", "20100102.203040"},
+	{"parses dt+number", "20100102203040-10", "20100102203040.10"},
+	{"parses dt+patch", "20100102-203040-p1", "20100102.203040-patch1"},
+	{"parses dt Ym", "201903.0", "201903.0"},
+	{"parses dt Ym+patch", "201903.0-p2", "201903.0-patch2"},
+	{"parses master", "dev-master", "dev-master"},
+	{"parses master w/o dev", "master", "dev-master"},
+	{"parses trunk", "dev-trunk", "dev-trunk"},
+	{"parses branches", "1.x-dev", "1.9999999.9999999.9999999-dev"},
+	{"parses arbitrary", "dev-feature-foo", "dev-feature-foo"},
+	{"parses arbitrary/2", "DEV-FOOBAR", "dev-FOOBAR"},
+	{"parses arbitrary/3", "dev-feature/foo", "dev-feature/foo"},
+	{"parses arbitrary/4", "dev-feature+issue-1", "dev-feature+issue-1"},
+	{"ignores aliases", "dev-master as 1.0.0", "dev-master"},
+	{"ignores aliases/2", "dev-load-varnish-only-when-used as ^2.0", "dev-load-varnish-only-when-used"},
+	{"ignores aliases/3", "dev-load-varnish-only-when-used@dev as ^2.0@dev", "dev-load-varnish-only-when-used"},
+	{"ignores stability", "1.0.0+foo@dev", "1.0.0.0"},
+	{"ignores stability/2", "dev-load-varnish-only-when-used@stable", "dev-load-varnish-only-when-used"},
+	{"semver metadata/2", "1.0.0-beta.5+foo", "1.0.0.0-beta5"},
+	{"semver metadata/3", "1.0.0+foo", "1.0.0.0"},
+	{"semver metadata/4", "1.0.0-alpha.3.1+foo", "1.0.0.0-alpha3.1"},
+	{"semver metadata/5", "1.0.0-alpha2.1+foo", "1.0.0.0-alpha2.1"},
+	{"semver metadata/6", "1.0.0-alpha-2.1-3+foo", "1.0.0.0-alpha2.1-3"},
+	{"metadata w/ alias", "1.0.0+foo as 2.0", "1.0.0.0"},
+	{"keep zero-padding", "00.01.03.04", "00.01.03.04"},
+	{"keep zero-padding/2", "000.001.003.004", "000.001.003.004"},
+	{"keep zero-padding/3", "0.000.103.204", "0.000.103.204"},
+	{"keep zero-padding/4", "0700", "0700.0.0.0"},
+	{"keep zero-padding/5", "041.x-dev", "041.9999999.9999999.9999999-dev"},
+	{"keep zero-padding/6", "dev-041.003", "dev-041.003"},
+	{"dev with mad name", "dev-1.0.0-dev<1.0.5-dev", "dev-1.0.0-dev<1.0.5-dev"},
+	{"dev prefix with spaces", "dev-foo bar", "dev-foo bar"},
+	{"space padding", " 1.0.0", "1.0.0.0"},
+	{"space padding/2", "1.0.0 ", "1.0.0.0"},
+}
+
+var composerFailingNormalizeCases = []struct {
+	name    string
+	version string
+}{
+	{"empty ", ""},
+	{"invalid chars", "a"},
+	{"invalid type", "1.0.0-meh"},
+	{"too many bits", "1.0.0.0.0"},
+	{"non-dev arbitrary", "feature-foo"},
+	{"metadata w/ space", "1.0.0+foo bar"},
+	{"maven style release", "1.0.1-SNAPSHOT"},
+	{"dev with less than", "1.0.0<1.0.5-dev"},
+	{"dev with less than/2", "1.0.0-dev<1.0.5-dev"},
+	{"dev suffix with spaces", "foo bar-dev"},
+	{"any with spaces", "1.0 .2"},
+	{"no version, no alias", " as "},
+	{"no version, only alias", " as 1.2"},
+	{"just an operator", "^"},
+	{"just an operator/3", "~"},
+	{"constraint", "~1"},
+	{"constraint/2", "^1"},
+	{"constraint/3", "1.*"},
+	{"date versions with 4 bits", "20100102.0.3.4"},
+	{"invalid CalVer (as MAJOR) versions", "20100102.1.0"},
+	{"invalid CalVer (as MAJOR) versions/2", "20100102.1.0.1"},
+}
+
+var composerConstraintCases = []struct {
+	name       string
+	constraint string
+	want       string
+}{
+	// simpleConstraints
+	{"match any", "*", "*"},
+	{"match any/2", "*.*", ">= 0.0.0.0-dev"},
+	{"match any/2v", "v*.*", ">= 0.0.0.0-dev"},
+	{"match any/3", "*.x.*", ">= 0.0.0.0-dev"},
+	{"match any/4", "x.X.x.*", ">= 0.0.0.0-dev"},
+	{"not equal", "<>1.0.0", "!= 1.0.0.0"},
+	{"not equal/2", "!=1.0.0", "!= 1.0.0.0"},
+	{"greater than", ">1.0.0", "> 1.0.0.0"},
+	{"lesser than", "<1.2.3.4", "< 1.2.3.4-dev"},
+	{"less/eq than", "<=1.2.3", "<= 1.2.3.0"},
+	{"great/eq than", ">=1.2.3", ">= 1.2.3.0-dev"},
+	{"equals", "=1.2.3", "== 1.2.3.0"},
+	{"double equals", "==1.2.3", "== 1.2.3.0"},
+	{"no op means eq", "1.2.3", "== 1.2.3.0"},
+	{"completes version", "=1.0", "== 1.0.0.0"},
+	{"shorthand beta", "1.2.3b5", "== 1.2.3.0-beta5"},
+	{"shorthand alpha", "1.2.3a1", "== 1.2.3.0-alpha1"},
+	{"shorthand patch", "1.2.3p1234", "== 1.2.3.0-patch1234"},
+	{"shorthand patch/2", "1.2.3pl1234", "== 1.2.3.0-patch1234"},
+	{"accepts spaces", ">= 1.2.3", ">= 1.2.3.0-dev"},
+	{"accepts spaces/2", "< 1.2.3", "< 1.2.3.0-dev"},
+	{"accepts spaces/3", "> 1.2.3", "> 1.2.3.0"},
+	{"accepts master", ">=dev-master", ">= dev-master"},
+	{"accepts master/2", "dev-master", "== dev-master"},
+	{"accepts arbitrary", "dev-feature-a", "== dev-feature-a"},
+	{"regression #550", "dev-some-fix", "== dev-some-fix"},
+	{"regression #935", "dev-CAPS", "== dev-CAPS"},
+	{"ignores aliases", "dev-master as 1.0.0", "== dev-master"},
+	{"lesser than override", "<1.2.3.4-stable", "< 1.2.3.4"},
+	{"great/eq than override", ">=1.2.3.4-stable", ">= 1.2.3.4"},
+	{"foobar-dev is dev-foobar", "foobar-dev", "== dev-foobar"},
+	{"strips commit refs", "dev-master#abcdef", "== dev-master"},
+	{"stability flag", "1.0@dev", "== 1.0.0.0"},
+	{"stability flag/2", ">=1.0@beta", ">= 1.0.0.0-beta"},
+	{"stability flag only", "@dev", "*"},
+
+	// wildcardConstraints
+	{"wildcard", "v2.*", "[>= 2.0.0.0-dev < 3.0.0.0-dev]"},
+	{"wildcard/2", "2.*.*", "[>= 2.0.0.0-dev < 3.0.0.0-dev]"},
+	{"wildcard/3", "20.*", "[>= 20.0.0.0-dev < 21.0.0.0-dev]"},
+	{"wildcard/4", "20.*.*", "[>= 20.0.0.0-dev < 21.0.0.0-dev]"},
+	{"wildcard/5", "2.0.*", "[>= 2.0.0.0-dev < 2.1.0.0-dev]"},
+	{"wildcard/6", "2.x", "[>= 2.0.0.0-dev < 3.0.0.0-dev]"},
+	{"wildcard/7", "2.x.x", "[>= 2.0.0.0-dev < 3.0.0.0-dev]"},
+	{"wildcard/8", "2.2.x", "[>= 2.2.0.0-dev < 2.3.0.0-dev]"},
+	{"wildcard/9", "2.10.X", "[>= 2.10.0.0-dev < 2.11.0.0-dev]"},
+	{"wildcard/10", "2.1.3.*", "[>= 2.1.3.0-dev < 2.1.4.0-dev]"},
+	{"wildcard/11", "0.*", "< 1.0.0.0-dev"},
+	{"wildcard/12", "0.*.*", "< 1.0.0.0-dev"},
+	{"wildcard/13", "0.x", "< 1.0.0.0-dev"},
+
+	// tildeConstraints
+	{"tilde", "~v1", "[>= 1.0.0.0-dev < 2.0.0.0-dev]"},
+	{"tilde/2", "~1.0", "[>= 1.0.0.0-dev < 2.0.0.0-dev]"},
+	{"tilde/3", "~1.0.0", "[>= 1.0.0.0-dev < 1.1.0.0-dev]"},
+	{"tilde/4", "~1.2", "[>= 1.2.0.0-dev < 2.0.0.0-dev]"},
+	{"tilde/5", "~1.2.3", "[>= 1.2.3.0-dev < 1.3.0.0-dev]"},
+	{"tilde/6", "~1.2.3.4", "[>= 1.2.3.4-dev < 1.2.4.0-dev]"},
+	{"tilde/7", "~1.2-beta", "[>= 1.2.0.0-beta < 2.0.0.0-dev]"},
+	{"tilde/8", "~1.2-b2", "[>= 1.2.0.0-beta2 < 2.0.0.0-dev]"},
+	{"tilde/9", "~1.2-BETA2", "[>= 1.2.0.0-beta2 < 2.0.0.0-dev]"},
+	{"tilde/10", "~1.2.2-dev", "[>= 1.2.2.0-dev < 1.3.0.0-dev]"},
+	{"tilde/11", "~1.2.2-stable", "[>= 1.2.2.0 < 1.3.0.0-dev]"},
+
+	// caretConstraints
+	{"caret", "^v1", "[>= 1.0.0.0-dev < 2.0.0.0-dev]"},
+	{"caret/2", "^0", "[>= 0.0.0.0-dev < 1.0.0.0-dev]"},
+	{"caret/3", "^0.0", "[>= 0.0.0.0-dev < 0.1.0.0-dev]"},
+	{"caret/4", "^1.2", "[>= 1.2.0.0-dev < 2.0.0.0-dev]"},
+	{"caret/5", "^1.2.3-beta.2", "[>= 1.2.3.0-beta2 < 2.0.0.0-dev]"},
+	{"caret/6", "^1.2.3.4", "[>= 1.2.3.4-dev < 2.0.0.0-dev]"},
+	{"caret/7", "^1.2.3", "[>= 1.2.3.0-dev < 2.0.0.0-dev]"},
+	{"caret/8", "^0.2.3", "[>= 0.2.3.0-dev < 0.3.0.0-dev]"},
+	{"caret/9", "^0.2", "[>= 0.2.0.0-dev < 0.3.0.0-dev]"},
+	{"caret/10", "^0.2.0", "[>= 0.2.0.0-dev < 0.3.0.0-dev]"},
+	{"caret/11", "^0.0.3", "[>= 0.0.3.0-dev < 0.0.4.0-dev]"},
+	{"caret/12", "^0.0.3-alpha", "[>= 0.0.3.0-alpha < 0.0.4.0-dev]"},
+	{"caret/13", "^0.0.3-dev", "[>= 0.0.3.0-dev < 0.0.4.0-dev]"},
+
+	// hyphenConstraints
+	{"hyphen", "1 - 2", "[>= 1.0.0.0-dev < 3.0.0.0-dev]"},
+	{"hyphen/2", "1.2.3 - 2.3.4.5", "[>= 1.2.3.0-dev <= 2.3.4.5]"},
+	{"hyphen/3", "1.2-beta - 2.3", "[>= 1.2.0.0-beta < 2.4.0.0-dev]"},
+	{"hyphen/4", "1.2-beta - 2.3-dev", "[>= 1.2.0.0-beta <= 2.3.0.0-dev]"},
+	{"hyphen/5", "1.2-RC - 2.3.1", "[>= 1.2.0.0-RC <= 2.3.1.0]"},
+	{"hyphen/6", "1.2.3-alpha - 2.3-RC", "[>= 1.2.3.0-alpha <= 2.3.0.0-RC]"},
+	{"hyphen/7", "1 - 2.0", "[>= 1.0.0.0-dev < 2.1.0.0-dev]"},
+	{"hyphen/8", "1 - 2.1", "[>= 1.0.0.0-dev < 2.2.0.0-dev]"},
+	{"hyphen/9", "1.2 - 2.1.0", "[>= 1.2.0.0-dev <= 2.1.0.0]"},
+	{"hyphen/10", "1.3 - 2.1.3", "[>= 1.3.0.0-dev <= 2.1.3.0]"},
+
+	// multiConstraints
+	{"multi", ">2.0,<=3.0", "[> 2.0.0.0 <= 3.0.0.0]"},
+	{"multi/2", ">2.0 <=3.0", "[> 2.0.0.0 <= 3.0.0.0]"},
+	{"multi/3", ">2.0  <=3.0", "[> 2.0.0.0 <= 3.0.0.0]"},
+	{"multi/4", ">2.0, <=3.0", "[> 2.0.0.0 <= 3.0.0.0]"},
+	{"multi/5", ">2.0 ,<=3.0", "[> 2.0.0.0 <= 3.0.0.0]"},
+	{"multi/6", ">2.0 , <=3.0", "[> 2.0.0.0 <= 3.0.0.0]"},
+	{"multi/7", ">2.0   , <=3.0", "[> 2.0.0.0 <= 3.0.0.0]"},
+	{"multi/8", "> 2.0   <=  3.0", "[> 2.0.0.0 <= 3.0.0.0]"},
+	{"multi/9", "> 2.0  ,  <=  3.0", "[> 2.0.0.0 <= 3.0.0.0]"},
+	{"multi/10", "  > 2.0  ,  <=  3.0 ", "[> 2.0.0.0 <= 3.0.0.0]"},
+	{"multi with stability", ">2.0@stable,<=3.0@dev", "[> 2.0.0.0 <= 3.0.0.0-dev]"},
+	{"multi with hyphen", "1.0 - 2.0, !=1.5.0", "[>= 1.0.0.0-dev < 2.1.0.0-dev != 1.5.0.0]"},
+	{"disjunctive", ">2.0,<2.0.5 | >2.0.6", "[[> 2.0.0.0 < 2.0.5.0-dev] || > 2.0.6.0]"},
+	{"disjunctive/2", ">2.0,<2.0.5 || >2.0.6", "[[> 2.0.0.0 < 2.0.5.0-dev] || > 2.0.6.0]"},
+	{"disjunctive/3", "^1.0 || ^2.0", "[[>= 1.0.0.0-dev < 2.0.0.0-dev] || [>= 2.0.0.0-dev < 3.0.0.0-dev]]"},
+	{"advisory range", ">=0 <1.2.3 || >=2.0.0 <2.1.0", "[[>= 0.0.0.0-dev < 1.2.3.0-dev] || [>= 2.0.0.0-dev < 2.1.0.0-dev]]"},
+}
+
+var composerFailingConstraintCases = []struct {
+	name       string
+	constraint string
+}{
+	{"empty", ""},
+	{"invalid version", "1.0.0-meh"},
+	{"operator abuse", ">2.0,,<=3.0"},
+	{"operator abuse/2", ">2.0 ,, <=3.0"},
+	{"operator abuse/3", ">2.0 ||| <=3.0"},
+	{"just an operator", "^"},
+	{"just an operator/2", "^8 || ^"},
+	{"just an operator/3", "~"},
+	{"just an operator/4", "~1 ~"},
+	{"pessimistic operator", "~>1.2"},
+}
+
+var composerCompareCases = []struct {
+	a, b string
+	want int
+}{
+	{"1.0.0.0", "1.0.0.0", 0},
+	{"1.0.0.0-dev", "1.0.0.0-alpha1", -1},
+	{"1.0.0.0-alpha1", "1.0.0.0-beta1", -1},
+	{"1.0.0.0-beta2", "1.0.0.0-beta10", -1},
+	{"1.0.0.0-beta10", "1.0.0.0-RC1", -1},
+	{"1.0.0.0-RC1", "1.0.0.0", -1},
+	{"1.0.0.0", "1.0.0.0-patch1", -1},
+	{"1.0.0.0-RC1-dev", "1.0.0.0-RC1", -1},
+	{"1.0.9.0", "1.0.10.0", -1},
+	{"5.2", "5.2.0", -1},
+	{"1.0rc1", "1.0", -1},
+	{"1.0pl1", "1.0", 1},
+	{"1.9999999.9999999.9999999-dev", "2.0.0.0-dev", -1},
+	{"dev-master", "1.0.0.0", -1},
+}
+
+// composerSatisfiesCases はstabilityを考えずに制約を満たすかどうか(Semver::satisfies)
+var composerSatisfiesCases = []struct {
+	version    string
+	constraint string
+	want       bool
+}{
+	{"1.2.3", "1.0.0 - 2.0.0", true},
+	{"1.2.3", "^1.2.3+build", true},
+	{"1.3.0", "^1.2.3+build", true},
+	{"2.4.3-alpha", "1.2.3+asdf - 2.4.3+asdf", true},
+	{"1.3.0-beta", ">1.2", true},
+	{"1.2.3-beta", "<=1.2.3", true},
+	{"1.2.3-beta", "^1.2.3-beta", true},
+	{"2.0.0", ">1.2", true},
+	{"1.2.3", "~1.2", true},
+	{"1.2.3", "1.2.3 || 1.2.4", true},
+	{"2.1.3", "~2.1", true},
+	{"1.2.0", "1.2.*", true},
+	{"v1.2.9", "1.2.*", true},
+	{"1.9.9", "~1.2", true},
+	{"0.0.3", "^0.0.3", true},
+	{"0.2.9", "^0.2.3", true},
+	{"dev-master", "dev-master", true},
+	{"dev-master", "!=dev-feature", true},
+	{"1.x-dev", "~1.0", true},
+	{"2.2.3", "1.0.0 - 2.0.0", false},
+	{"2.0.0", "^1.2.3+build", false},
+	{"1.2.0", "^1.2.3+build", false},
+	{"1.0.0beta", "1", false},
+	{"3.0.0", "~2.1", false},
+	{"1.3.0", "~1.2.3", false},
+	{"0.0.4", "^0.0.3", false},
+	{"0.3.0", "^0.2.3", false},
+	{"1.3.0", "1.2.*", false},
+	{"dev-master", ">=1.0", false},
+	{"dev-master", "dev-feature", false},
+	{"dev-master", ">=dev-master", false},
+}
+
+// composerCheckCases はminimum-stabilityがstableのときに制約を満たすかどうか(Check)
+var composerCheckCases = []struct {
+	version    string
+	constraint string
+	want       bool
+}{
+	{"1.2.3", "^1.2", true},
+	{"1.3.0-beta1", "^1.2", false},
+	{"1.3.0-beta1", "^1.2@beta", true},
+	{"1.3.0-alpha1", "^1.2@beta", false},
+	{"1.3.0-RC1", ">=1.3.0-RC1", true},
+	{"1.3.0-beta2", "^1.3.0-beta.1", true},
+	{"dev-master", "dev-master", true},
+	{"dev-master", "*", false},
+	{"dev-master", "*@dev", true},
+	{"1.x-dev", "1.x-dev", true},
+	{"1.0.0-patch1", "^1.0", true},
+}
+
+func TestNormalizeComposerVersion(t *testing.T) {
+	for _, c := range composerNormalizeCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			got, err := normalizeComposerVersion(c.version)
+			if err != nil {
+				t.Fatalf("normalize(%q): %s", c.version, err)
+			}
+			if got != c.want {
+				t.Errorf("normalize(%q) = %q, want %q", c.version, got, c.want)
+			}
+		})
+	}
+	for _, c := range composerFailingNormalizeCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			if got, err := normalizeComposerVersion(c.version); err == nil {
+				t.Errorf("normalize(%q) = %q, want error", c.version, got)
+			}
+		})
+	}
+}
+
+func TestNewComposerConstraint(t *testing.T) {
+	for _, c := range composerConstraintCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			constraint, err := NewComposerConstraint(c.constraint)
+			if err != nil {
+				t.Fatalf("parse(%q): %s", c.constraint, err)
+			}
+			if got := constraint.normalizedString(); got != c.want {
+				t.Errorf("parse(%q) = %q, want %q", c.constraint, got, c.want)
+			}
+		})
+	}
+	for _, c := range composerFailingConstraintCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			if constraint, err := NewComposerConstraint(c.constraint); err == nil {
+				t.Errorf("parse(%q) = %q, want error", c.constraint, constraint.normalizedString())
+			}
+		})
+	}
+}
+
+func TestPHPVersionCompare(t *testing.T) {
+	for _, c := range composerCompareCases {
+		if got := phpVersionCompare(c.a, c.b); got != c.want {
+			t.Errorf("version_compare(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+		if got := phpVersionCompare(c.b, c.a); got != -c.want {
+			t.Errorf("version_compare(%q, %q) = %d, want %d", c.b, c.a, got, -c.want)
+		}
+	}
+}
+
+func TestComposerConstraintMatches(t *testing.T) {
+	for _, c := range composerSatisfiesCases {
+		v, err := NewComposerVersion(c.version)
+		if err != nil {
+			t.Errorf("version %q: %s", c.version, err)
+			continue
+		}
+		constraint, err := NewComposerConstraint(c.constraint)
+		if err != nil {
+			t.Errorf("constraint %q: %s", c.constraint, err)
+			continue
+		}
+		if got := constraint.matches(v.Normalized()); got != c.want {
+			t.Errorf("satisfies(%q, %q) = %v, want %v", c.version, c.constraint, got, c.want)
+		}
+	}
+}
+
+func TestComposerConstraintCheck(t *testing.T) {
+	for _, c := range composerCheckCases {
+		v, err := NewComposerVersion(c.version)
+		if err != nil {
+			t.Errorf("version %q: %s", c.version, err)
+			continue
+		}
+		constraint, err := NewComposerConstraint(c.constraint)
+		if err != nil {
+			t.Errorf("constraint %q: %s", c.constraint, err)
+			continue
+		}
+		if got := constraint.Check(v); got != c.want {
+			t.Errorf("Check(%q, %q) = %v, want %v", c.version, c.constraint, got, c.want)
+		}
+	}
+
+	constraint, err := NewComposerConstraint("self.version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if constraint.Resolvable() {
+		t.Errorf("self.version should not be resolvable")
+	}
+}
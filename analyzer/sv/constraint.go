@@ -0,0 +1,100 @@
+package sv
+
+import (
+	"analyzer/models"
+	semver "github.com/Masterminds/semver/v3"
+	"strings"
+)
+
+// Constraint はエコシステムの規則で解釈した依存関係制約や脆弱性のバージョン範囲
+type Constraint interface {
+	// Check はバージョンが制約を満たすかどうかを返す. vは同じエコシステムのバージョン(NewVersion)でなければならない
+	Check(v Version) bool
+	// Resolvable はレジストリに公開されたバージョンに解決される制約かどうかを返す.
+	// gitリポジトリやローカルのファイルを指す指定はfalseになり、Checkは常にfalseを返す
+	Resolvable() bool
+	String() string
+}
+
+// NewConstraint はecosystemの規則で制約を解釈する. 専用の規則が無いエコシステムはMastermindsのsemverで解釈する
+func NewConstraint(ecosystem models.EcosystemType, constraint string) (Constraint, error) {
+	switch ecosystem {
+	case models.Npm:
+		return NewNpmConstraint(constraint)
+	case models.Cargo:
+		return newUnionConstraint(constraint, func(s string) (Constraint, error) {
+			return NewCargoConstraint(s)
+		})
+	case models.RubyGems:
+		return newUnionConstraint(constraint, func(s string) (Constraint, error) {
+			return NewGemRequirement(s)
+		})
+	case models.Packagist:
+		return NewComposerConstraint(constraint)
+	default:
+		c, err := semver.NewConstraint(constraint)
+		if err != nil {
+			return nil, err
+		}
+		return semverConstraint{c}, nil
+	}
+}
+
+// semverConstraint はMastermindsのsemverで解釈した制約
+type semverConstraint struct {
+	*semver.Constraints
+}
+
+func (c semverConstraint) Check(v Version) bool {
+	s, ok := v.(semVersion)
+	return ok && c.Constraints.Check(s.Version)
+}
+
+func (c semverConstraint) Resolvable() bool {
+	return true
+}
+
+// unionConstraint は || で区切ったいずれかの制約を満たす制約.
+// エコシステムの制約の書き方には無いが、脆弱性のバージョン範囲とDerivedConstraintは || で範囲を並べる
+type unionConstraint struct {
+	constraint  string
+	constraints []Constraint
+}
+
+func newUnionConstraint(constraint string, parse func(s string) (Constraint, error)) (Constraint, error) {
+	parts := strings.Split(constraint, "||")
+	if len(parts) == 1 {
+		return parse(constraint)
+	}
+
+	constraints := make([]Constraint, 0, len(parts))
+	for _, part := range parts {
+		c, err := parse(part)
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, c)
+	}
+	return unionConstraint{constraint: constraint, constraints: constraints}, nil
+}
+
+func (c unionConstraint) Check(v Version) bool {
+	for _, constraint := range c.constraints {
+		if constraint.Check(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c unionConstraint) Resolvable() bool {
+	return true
+}
+
+func (c unionConstraint) String() string {
+	return c.constraint
+}
+
+func (c unionConstraint) toIntervals() ([]interval, error) {
+	return unionIntervals(c.constraints)
+}
@@ -0,0 +1,323 @@
+package sv
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	gemVersionPattern   = regexp.MustCompile(`^[0-9]+(?:\.[0-9a-zA-Z]+)*(?:-[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*)?$`)
+	gemSegmentPattern   = regexp.MustCompile(`[0-9]+|[a-zA-Z]+`)
+	gemOperatorSpacing  = regexp.MustCompile(`(~>|!=|>=|<=|>|<|=)\s+`)
+	gemOperators        = []string{"~>", "!=", ">=", "<=", ">", "<", "="}
+	gemDefaultCondition = gemCondition{op: ">=", version: &GemVersion{version: "0", segments: []gemSegment{{value: "0", numeric: true}}}}
+)
+
+// GemVersion はGem::Versionと同じ規則で解釈したRubyGemsのバージョン.
+// 1.2.3.4 のように4つ以上の部分や、2.0.0.rc1 のように英字を含むことができ、英字を含むものはプレリリースになる
+type GemVersion struct {
+	version  string
+	segments []gemSegment
+}
+
+// gemSegment はバージョンを数字と英字の並びに分けたそれぞれ. 2.0.0.rc1 は 2, 0, 0, rc, 1 になる
+type gemSegment struct {
+	value   string
+	numeric bool
+}
+
+func NewGemVersion(version string) (*GemVersion, error) {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		// Gem::Versionと同じく、空は0とみなす
+		version = "0"
+	}
+	if !gemVersionPattern.MatchString(version) {
+		return nil, fmt.Errorf("RubyGemsのバージョンとして解釈できません. version: '%s'", version)
+	}
+
+	// 1.0-java は 1.0.pre.java と同じ
+	segments := make([]gemSegment, 0)
+	for _, s := range gemSegmentPattern.FindAllString(strings.ReplaceAll(version, "-", ".pre."), -1) {
+		numeric := s[0] >= '0' && s[0] <= '9'
+		if numeric {
+			s = strings.TrimLeft(s, "0")
+			if s == "" {
+				s = "0"
+			}
+		}
+		segments = append(segments, gemSegment{value: s, numeric: numeric})
+	}
+	return &GemVersion{version: version, segments: segments}, nil
+}
+
+func (v *GemVersion) String() string {
+	return v.version
+}
+
+func (v *GemVersion) Major() uint64 {
+	return v.number(0)
+}
+
+func (v *GemVersion) Minor() uint64 {
+	return v.number(1)
+}
+
+func (v *GemVersion) Patch() uint64 {
+	return v.number(2)
+}
+
+// number は先頭から続く数字の部分のi番目. 無ければ0
+func (v *GemVersion) number(i int) uint64 {
+	release := v.release()
+	if i >= len(release.segments) {
+		return 0
+	}
+	n, err := strconv.ParseUint(release.segments[i].value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// Prerelease は英字を含む(1.0-java のように - を含むものも含む)バージョンかどうかを返す
+func (v *GemVersion) Prerelease() bool {
+	for _, s := range v.segments {
+		if !s.numeric {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *GemVersion) Compare(o Version) int {
+	return compareGemSegments(v.canonicalSegments(), o.(*GemVersion).canonicalSegments())
+}
+
+// canonicalSegments は末尾の0を除いた部分の並び. 数字だけの部分と英字から始まる部分それぞれの末尾の0を除くので、
+// 1.0 と 1、1.0.a と 1.a は同じになる
+func (v *GemVersion) canonicalSegments() []gemSegment {
+	release := v.release().segments
+	pre := v.segments[len(release):]
+	return append(trimGemZeros(release), trimGemZeros(pre)...)
+}
+
+func trimGemZeros(segments []gemSegment) []gemSegment {
+	end := len(segments)
+	for end > 0 && segments[end-1].numeric && segments[end-1].value == "0" {
+		end--
+	}
+	return append([]gemSegment{}, segments[:end]...)
+}
+
+// release は英字の部分より前の、プレリリースでないバージョン. 2.0.0.rc1 なら 2.0.0
+func (v *GemVersion) release() *GemVersion {
+	end := len(v.segments)
+	for i, s := range v.segments {
+		if !s.numeric {
+			end = i
+			break
+		}
+	}
+	if end == len(v.segments) {
+		return v
+	}
+	return newGemVersionFromSegments(v.segments[:end])
+}
+
+// bump は最後の部分を除いて、その1つ前の部分を1つ上げたバージョン. ~> の上限になる.
+// 1.2.3 なら 1.3、1.2 なら 2、2.0.0.rc1 なら 2.1
+func (v *GemVersion) bump() *GemVersion {
+	segments := append([]gemSegment{}, v.release().segments...)
+	if len(segments) > 1 {
+		segments = segments[:len(segments)-1]
+	}
+	last := len(segments) - 1
+	n, err := strconv.ParseUint(segments[last].value, 10, 64)
+	if err != nil {
+		// uint64に収まらない部分は上げられないので、そのままにする
+		return newGemVersionFromSegments(segments)
+	}
+	segments[last] = gemSegment{value: strconv.FormatUint(n+1, 10), numeric: true}
+	return newGemVersionFromSegments(segments)
+}
+
+func newGemVersionFromSegments(segments []gemSegment) *GemVersion {
+	values := make([]string, 0, len(segments))
+	for _, s := range segments {
+		values = append(values, s.value)
+	}
+	return &GemVersion{version: strings.Join(values, "."), segments: segments}
+}
+
+// compareGemSegments はGem::Version#<=>と同じく前から比べる. 足りない部分は0とし、英字は数字より小さい
+func compareGemSegments(a []gemSegment, b []gemSegment) int {
+	zero := gemSegment{value: "0", numeric: true}
+	for i := 0; i < len(a) || i < len(b); i++ {
+		l, r := zero, zero
+		if i < len(a) {
+			l = a[i]
+		}
+		if i < len(b) {
+			r = b[i]
+		}
+		switch {
+		case l == r:
+			continue
+		case !l.numeric && r.numeric:
+			return -1
+		case l.numeric && !r.numeric:
+			return 1
+		case l.numeric:
+			// 先頭の0は除いてあるので、桁数が多いほうが大きい
+			if len(l.value) != len(r.value) {
+				return compareInt(len(l.value), len(r.value))
+			}
+			return strings.Compare(l.value, r.value)
+		default:
+			return strings.Compare(l.value, r.value)
+		}
+	}
+	return 0
+}
+
+func compareInt(a int, b int) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// GemRequirement はGem::Requirementと同じ規則で解釈したRubyGemsの依存関係制約.
+// 全ての条件を満たすバージョンが制約を満たす. ただし依存関係の解決と同じく、
+// 条件にプレリリースのバージョンを含まない制約はプレリリースのバージョンを満たさない
+type GemRequirement struct {
+	requirement string
+	conditions  []gemCondition
+}
+
+type gemCondition struct {
+	op      string
+	version *GemVersion
+}
+
+// NewGemRequirement はGemfileやgemspecの依存関係制約(~> 1.2, >= 1.2.3)を解釈する.
+// 条件はカンマで区切るが、脆弱性のバージョン範囲(>=0 <1.4.0)のように空白で区切ったものも受け付ける
+func NewGemRequirement(requirement string) (*GemRequirement, error) {
+	fields := strings.FieldsFunc(gemOperatorSpacing.ReplaceAllString(strings.TrimSpace(requirement), "$1"), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(fields) == 0 {
+		// Gem::Requirement.defaultと同じく、指定が無ければ >= 0
+		return &GemRequirement{requirement: requirement, conditions: []gemCondition{gemDefaultCondition}}, nil
+	}
+
+	conditions := make([]gemCondition, 0, len(fields))
+	for _, field := range fields {
+		op := "="
+		for _, o := range gemOperators {
+			if strings.HasPrefix(field, o) {
+				op = o
+				field = field[len(o):]
+				break
+			}
+		}
+		if field == "" {
+			return nil, fmt.Errorf("RubyGemsの依存関係制約として解釈できません. requirement: '%s'", requirement)
+		}
+		v, err := NewGemVersion(field)
+		if err != nil {
+			return nil, fmt.Errorf("RubyGemsの依存関係制約として解釈できません. requirement: '%s': %w", requirement, err)
+		}
+		conditions = append(conditions, gemCondition{op: op, version: v})
+	}
+	return &GemRequirement{requirement: requirement, conditions: conditions}, nil
+}
+
+func (r *GemRequirement) Check(v Version) bool {
+	gv, ok := v.(*GemVersion)
+	if !ok {
+		return false
+	}
+	if gv.Prerelease() && !r.prerelease() {
+		return false
+	}
+	for _, c := range r.conditions {
+		if !c.check(gv) {
+			return false
+		}
+	}
+	return true
+}
+
+// prerelease は条件にプレリリースのバージョンを含むかどうかを返す
+func (r *GemRequirement) prerelease() bool {
+	for _, c := range r.conditions {
+		if c.version.Prerelease() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *GemRequirement) Resolvable() bool {
+	return true
+}
+
+func (r *GemRequirement) String() string {
+	return r.requirement
+}
+
+func (r *GemRequirement) toIntervals() ([]interval, error) {
+	result := anyVersion()
+	for _, c := range r.conditions {
+		result = intersectIntervals(result, c.intervals())
+	}
+	return result, nil
+}
+
+func (c gemCondition) check(v *GemVersion) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	default:
+		// ~> 1.2.3 は >= 1.2.3 かつ < 1.3
+		return cmp >= 0 && v.release().Compare(c.version.bump()) < 0
+	}
+}
+
+// intervals は条件を満たすバージョンの区間を返す
+func (c gemCondition) intervals() []interval {
+	switch c.op {
+	case "=":
+		return exactly(c.version)
+	case "!=":
+		return notEqual(c.version)
+	case ">":
+		return greaterThan(c.version)
+	case "<":
+		return lessThan(c.version)
+	case ">=":
+		return atLeast(c.version)
+	case "<=":
+		return atMost(c.version)
+	default:
+		return []interval{{lower: bound{version: c.version, inclusive: true}, upper: bound{version: c.version.bump()}}}
+	}
+}
@@ -0,0 +1,121 @@
+package sv
+
+import (
+	"analyzer/models"
+	"testing"
+)
+
+// gemCompareCases はGem::Version#<=>と同じ結果になるはずの、バージョンの比較
+var gemCompareCases = []struct {
+	a    string
+	b    string
+	want int
+}{
+	{"1.0", "1", 0},
+	{"1.0.0", "1", 0},
+	{"", "0", 0},
+	{"1.010", "1.10", 0},
+	{"1.8.2", "0.0.0", 1},
+	{"1.2.10", "1.2.9", 1},
+	{"1.2.3.4", "1.2.3", 1},
+	{"1.8.2", "1.8.2.a", 1},
+	{"1.8.2.b", "1.8.2.a", 1},
+	{"1.8.2.a10", "1.8.2.a9", 1},
+	{"2.0.0.rc1", "2.0.0", -1},
+	{"2.0.0.rc1", "2.0.0.beta2", 1},
+	{"2.0.0.rc", "2.0.0.rc1", -1},
+	{"5.a", "5.0.0.a", 0},
+	{"1.0.a", "1.a", 0},
+	{"0.beta.1", "0.0.beta.2", -1},
+	// - は .pre. と同じなので、rc より前になる
+	{"1.0.0-rc1", "1.0.0.rc1", -1},
+	{"1.0-java", "1.0", -1},
+}
+
+// gemRequirementCases はGem::Requirement#satisfied_by?と、依存関係の解決でのプレリリースの扱いに沿った結果
+var gemRequirementCases = []struct {
+	name        string
+	version     string
+	requirement string
+	want        bool
+}{
+	{"pessimistic/minor", "1.9", "~> 1.2", true},
+	{"pessimistic/minor lower", "1.2", "~> 1.2", true},
+	{"pessimistic/minor below", "1.1", "~> 1.2", false},
+	{"pessimistic/minor upper", "2.0", "~> 1.2", false},
+	{"pessimistic/patch", "1.2.9", "~> 1.2.3", true},
+	{"pessimistic/patch deeper", "1.2.3.4", "~> 1.2.3", true},
+	{"pessimistic/patch upper", "1.3", "~> 1.2.3", false},
+	{"pessimistic/major only", "1.9", "~> 1", true},
+	{"pessimistic/major only upper", "2.0", "~> 1", false},
+	{"pessimistic/prerelease", "2.0.0.rc2", "~> 2.0.0.rc1", true},
+	{"pessimistic/prerelease release", "2.0.5", "~> 2.0.0.rc1", true},
+	{"pessimistic/prerelease upper", "2.1", "~> 2.0.0.rc1", false},
+	{"pessimistic/and", "1.0.2", "~> 1.0, >= 1.0.3", false},
+	{"pessimistic/and satisfied", "1.5", "~> 1.0, >= 1.0.3", true},
+	{"range", "1.9", ">= 1.2.3, < 2", true},
+	{"range/space separated", "1.3.9", ">=0 <1.4.0", true},
+	{"not equal", "1.2.4", "!= 1.2.4", false},
+	{"not equal/other", "1.2.5", "!= 1.2.4", true},
+	{"exact", "1.2.0", "= 1.2", true},
+	{"exact/bare", "1.2", "1.2", true},
+	{"exact/bare other", "1.3", "1.2", false},
+	{"default", "5", "", true},
+	{"prerelease/not opted in", "2.0.0.rc1", ">= 1.0", false},
+	{"prerelease/opted in", "2.0.0.rc1", ">= 1.0.a", true},
+	{"prerelease/pessimistic", "1.5.beta", "~> 1.2", false},
+	{"or", "2.0.2", "< 1.2.5 || >= 2.0.0, < 2.0.3", true},
+	{"or/gap", "1.5", "< 1.2.5 || >= 2.0.0, < 2.0.3", false},
+}
+
+func TestGemVersionCompare(t *testing.T) {
+	for _, c := range gemCompareCases {
+		a, err := NewGemVersion(c.a)
+		if err != nil {
+			t.Errorf("version %q: %s", c.a, err)
+			continue
+		}
+		b, err := NewGemVersion(c.b)
+		if err != nil {
+			t.Errorf("version %q: %s", c.b, err)
+			continue
+		}
+		if got := a.Compare(b); got != c.want {
+			t.Errorf("%q <=> %q = %d, want %d", c.a, c.b, got, c.want)
+		}
+		if got := b.Compare(a); got != -c.want {
+			t.Errorf("%q <=> %q = %d, want %d", c.b, c.a, got, -c.want)
+		}
+	}
+
+	for _, version := range []string{"junk", "1.0\n2.0", "1..2", "1.2 3.4"} {
+		if _, err := NewGemVersion(version); err == nil {
+			t.Errorf("version %q should fail", version)
+		}
+	}
+}
+
+func TestGemRequirementCheck(t *testing.T) {
+	for _, c := range gemRequirementCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			v, err := NewVersion(models.RubyGems, c.version)
+			if err != nil {
+				t.Fatalf("version %q: %s", c.version, err)
+			}
+			requirement, err := NewConstraint(models.RubyGems, c.requirement)
+			if err != nil {
+				t.Fatalf("requirement %q: %s", c.requirement, err)
+			}
+			if got := requirement.Check(v); got != c.want {
+				t.Errorf("Check(%q, %q) = %v, want %v", c.version, c.requirement, got, c.want)
+			}
+		})
+	}
+
+	for _, requirement := range []string{"~>", ">= 1.x!", "1.2.3 foo"} {
+		if _, err := NewGemRequirement(requirement); err == nil {
+			t.Errorf("parse(%q) should fail", requirement)
+		}
+	}
+}
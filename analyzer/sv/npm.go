@@ -0,0 +1,266 @@
+package sv
+
+import (
+	"fmt"
+	semver "github.com/Masterminds/semver/v3"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// NpmSpecifierType はnpmの依存関係の指定(package.jsonのdependenciesの値)の種類.
+// npm-package-argと同じ順番で判定する
+type NpmSpecifierType string
+
+const (
+	// バージョンの範囲 (^1.2.3, 1.x, >=1.0.0 <2.0.0 など)
+	NpmRange NpmSpecifierType = "range"
+	// dist-tag (latest, next など)
+	NpmTag NpmSpecifierType = "tag"
+	// 別名でインストールする別のパッケージ (npm:other@^1.0.0)
+	NpmAlias NpmSpecifierType = "alias"
+	// gitリポジトリ (git+https://..., github:user/repo, user/repo など)
+	NpmGit NpmSpecifierType = "git"
+	// URLで指定したtarball (https://example.com/pkg.tgz)
+	NpmTarball NpmSpecifierType = "tarball"
+	// ローカルのディレクトリやtarball (file:../pkg, ./pkg, link:../pkg, workspace:*)
+	NpmFile NpmSpecifierType = "file"
+)
+
+var (
+	npmGitSSHPattern   = regexp.MustCompile(`^[^@]+@[^:.]+\.[^:]+:.+$`)
+	npmGitHubShorthand = regexp.MustCompile(`^[^./@:\s#~][^/@:\s#]*/[^/@:\s#]+(?:#.*)?$`)
+	npmHostedGitPrefix = regexp.MustCompile(`(?i)^(?:github|gitlab|bitbucket|gist):`)
+	npmURLPattern      = regexp.MustCompile(`(?i)^(?:git\+)?[a-z]+:`)
+	npmFilePattern     = regexp.MustCompile(`^(?:[.]|~/|/|[a-zA-Z]:)`)
+	npmTarballPattern  = regexp.MustCompile(`(?i)[.](?:tgz|tar\.gz|tar)$`)
+	npmTagPattern      = regexp.MustCompile(`^[A-Za-z0-9\-_.!~*'()]+$`)
+	npmOperatorSpacing = regexp.MustCompile(`(~>|~|\^|>=|<=|>|<|=)\s+`)
+	npmHostedGitHosts  = []string{"github.com", "gitlab.com", "bitbucket.org", "gist.github.com"}
+	npmPartialPattern  = regexp.MustCompile(`^[v=\s]*([0-9]+|[xX*])(?:\.([0-9]+|[xX*])(?:\.([0-9]+|[xX*])(?:-?((?:[0-9]+|[0-9]*[a-zA-Z-][a-zA-Z0-9-]*)(?:\.(?:[0-9]+|[0-9]*[a-zA-Z-][a-zA-Z0-9-]*))*))?(?:\+([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?)?)?$`)
+	npmOperators       = []string{"~>", "~", "^", ">=", "<=", ">", "<", "="}
+)
+
+// NpmConstraint はnode-semverと同じ規則で解釈したnpmの依存関係制約
+type NpmConstraint struct {
+	specifier string
+	Type      NpmSpecifierType
+	// TypeがNpmAliasのときの、実際にインストールされるパッケージ名
+	AliasName string
+	// 範囲を || で区切ったそれぞれの、全て満たす必要がある比較. nilなら解決できない
+	sets [][]comparator
+}
+
+// NewNpmConstraint はpackage.jsonの依存関係の指定を解釈する.
+// バージョンの範囲とlatestは範囲として、gitリポジトリやファイルなどレジストリ以外の指定とlatest以外のdist-tagは
+// 解決できない制約(Resolvableがfalse)として返す. どれにも当てはまらない指定はエラーにする
+func NewNpmConstraint(specifier string) (*NpmConstraint, error) {
+	c := &NpmConstraint{specifier: specifier, Type: ClassifyNpmSpecifier(specifier)}
+
+	switch c.Type {
+	case NpmRange:
+		sets, err := parseNpmRange(specifier)
+		if err != nil {
+			return nil, err
+		}
+		c.sets = sets
+	case NpmTag:
+		tag := strings.TrimSpace(specifier)
+		if !npmTagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("npmの依存関係の指定として解釈できません. specifier: '%s'", specifier)
+		}
+		// latestは公開されている中で最新の安定版を指すので、* と同じものとみなす.
+		// それ以外のdist-tagは、当時どのバージョンを指していたか分からない
+		if tag == "latest" {
+			c.sets = [][]comparator{{anyComparator}}
+		}
+	case NpmAlias:
+		name, spec := splitNpmAlias(strings.TrimSpace(specifier)[len("npm:"):])
+		if name == "" {
+			return nil, fmt.Errorf("npmの別名の指定にパッケージ名がありません. specifier: '%s'", specifier)
+		}
+		aliased, err := NewNpmConstraint(spec)
+		if err != nil {
+			return nil, err
+		}
+		if aliased.Type != NpmRange && aliased.Type != NpmTag {
+			return nil, fmt.Errorf("npmの別名にはバージョンの範囲かdist-tagを指定してください. specifier: '%s'", specifier)
+		}
+		c.AliasName = name
+		c.sets = aliased.sets
+	}
+	return c, nil
+}
+
+// ClassifyNpmSpecifier はpackage.jsonの依存関係の指定の種類を返す.
+// レジストリ以外を指していなければ、バージョンの範囲として解釈できるかどうかでNpmRangeかNpmTagにする
+func ClassifyNpmSpecifier(specifier string) NpmSpecifierType {
+	spec := strings.TrimSpace(specifier)
+	lower := strings.ToLower(spec)
+
+	switch {
+	case npmFilePattern.MatchString(spec) || strings.HasPrefix(lower, "file:") || strings.HasPrefix(lower, "link:") || strings.HasPrefix(lower, "workspace:"):
+		return NpmFile
+	case strings.HasPrefix(lower, "npm:"):
+		return NpmAlias
+	case npmHostedGitPrefix.MatchString(spec) || npmGitHubShorthand.MatchString(spec) || npmGitSSHPattern.MatchString(spec):
+		return NpmGit
+	case npmURLPattern.MatchString(spec):
+		if strings.HasPrefix(lower, "git+") || strings.HasPrefix(lower, "git:") || isNpmHostedGitURL(spec) {
+			return NpmGit
+		}
+		return NpmTarball
+	case strings.Contains(spec, "/") || npmTarballPattern.MatchString(spec):
+		return NpmFile
+	}
+
+	if _, err := parseNpmRange(spec); err == nil {
+		return NpmRange
+	}
+	return NpmTag
+}
+
+// isNpmHostedGitURL はGitHubなどのリポジトリを指すURL(https://github.com/user/repo など)かどうかを返す.
+// リポジトリの中のtarballを指すURLは含めない
+func isNpmHostedGitURL(spec string) bool {
+	u, err := url.Parse(spec)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || npmTarballPattern.MatchString(u.Path) {
+		return false
+	}
+	for _, host := range npmHostedGitHosts {
+		if strings.EqualFold(u.Host, host) {
+			return len(strings.Split(strings.Trim(u.Path, "/"), "/")) == 2
+		}
+	}
+	return false
+}
+
+// splitNpmAlias は別名の指定(npm:の後ろ)をパッケージ名と範囲に分ける. 範囲が無ければ * にする
+func splitNpmAlias(s string) (string, string) {
+	at := strings.Index(s, "@")
+	if strings.HasPrefix(s, "@") {
+		// スコープ付きのパッケージ名(@scope/name)
+		at = strings.Index(s[1:], "@")
+		if at >= 0 {
+			at++
+		}
+	}
+	if at < 0 {
+		return s, "*"
+	}
+	spec := s[at+1:]
+	if spec == "" {
+		spec = "*"
+	}
+	return s[:at], spec
+}
+
+func (c *NpmConstraint) Check(v Version) bool {
+	s, ok := v.(semVersion)
+	if !ok {
+		return false
+	}
+	for _, set := range c.sets {
+		if checkComparatorSet(set, s.Version) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *NpmConstraint) Resolvable() bool {
+	return c.sets != nil
+}
+
+func (c *NpmConstraint) String() string {
+	return c.specifier
+}
+
+func (c *NpmConstraint) toIntervals() ([]interval, error) {
+	if c.sets == nil {
+		return nil, fmt.Errorf("レジストリのバージョンに解決されない指定はバージョン範囲にできません. specifier: '%s'", c.specifier)
+	}
+	result := make([]interval, 0)
+	for _, set := range c.sets {
+		result = append(result, comparatorSetIntervals(set)...)
+	}
+	return normalizeIntervals(result), nil
+}
+
+// parseNpmRange は || で区切られた範囲を解釈する
+func parseNpmRange(s string) ([][]comparator, error) {
+	sets := make([][]comparator, 0)
+	for _, r := range strings.Split(s, "||") {
+		set, err := parseNpmComparatorSet(r)
+		if err != nil {
+			return nil, fmt.Errorf("npmのバージョンの範囲として解釈できません. range: '%s': %w", s, err)
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+// parseNpmComparatorSet は空白区切りの範囲1つ(1.2.3 - 2.3.4, >=1.2.3 <2, ^1.2 など)を比較の列にする.
+// 以前使っていたMasterminds/semverと同じく、カンマ区切り(>=1.2.3, <2)も空白区切りと同じに扱う
+func parseNpmComparatorSet(s string) ([]comparator, error) {
+	fields := strings.Fields(npmOperatorSpacing.ReplaceAllString(strings.ReplaceAll(strings.TrimSpace(s), ",", " "), "$1"))
+	if len(fields) == 0 {
+		return []comparator{anyComparator}, nil
+	}
+
+	if len(fields) == 3 && fields[1] == "-" {
+		from, err := parsePartial(npmPartialPattern, fields[0])
+		if err != nil {
+			return nil, err
+		}
+		to, err := parsePartial(npmPartialPattern, fields[2])
+		if err != nil {
+			return nil, err
+		}
+		return npmHyphenRange(from, to), nil
+	}
+
+	set := make([]comparator, 0, len(fields))
+	for _, field := range fields {
+		op := ""
+		for _, o := range npmOperators {
+			if strings.HasPrefix(field, o) {
+				op = o
+				break
+			}
+		}
+		p, err := parsePartial(npmPartialPattern, field[len(op):])
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case "~", "~>":
+			set = append(set, tildeRange(p)...)
+		case "^":
+			set = append(set, caretRange(p)...)
+		default:
+			set = append(set, primitiveRange(op, p)...)
+		}
+	}
+	return set, nil
+}
+
+// npmHyphenRange は from - to を >=from <=to にする. 省略された部分はx-rangeとして扱う
+func npmHyphenRange(from partial, to partial) []comparator {
+	set := make([]comparator, 0, 2)
+	if from.parts != 0 {
+		set = append(set, comparator{op: ">=", version: semver.New(from.major, from.minor, from.patch, from.prerelease, "")})
+	}
+	switch to.parts {
+	case 0:
+	case 3:
+		set = append(set, comparator{op: "<=", version: to.version()})
+	default:
+		set = append(set, comparator{op: "<", version: to.next()})
+	}
+	if len(set) == 0 {
+		set = append(set, anyComparator)
+	}
+	return set
+}
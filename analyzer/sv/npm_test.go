@@ -0,0 +1,131 @@
+package sv
+
+import (
+	"analyzer/models"
+	"testing"
+)
+
+// npmCheckCases はnode-semverのsatisfiesと同じ結果になるはずの、バージョンが範囲を満たすかどうか
+var npmCheckCases = []struct {
+	name       string
+	version    string
+	constraint string
+	want       bool
+}{
+	{"x-range", "1.9.9", "1.x", true},
+	{"x-range/lower", "0.9.9", "1.x", false},
+	{"x-range/upper", "2.0.0", "1.x", false},
+	{"x-range/minor", "1.2.9", "1.2.*", true},
+	{"x-range/minor upper", "1.3.0", "1.2.*", false},
+	{"x-range/major only", "1.5.0", "1", true},
+	{"x-range/major only upper", "2.0.0", "1", false},
+	{"x-range/any", "3.0.0", "x", true},
+	{"x-range/empty", "1.0.0", "", true},
+	{"hyphen", "1.2.3", "1.2.3 - 2.3.4", true},
+	{"hyphen/inclusive upper", "2.3.4", "1.2.3 - 2.3.4", true},
+	{"hyphen/upper", "2.3.5", "1.2.3 - 2.3.4", false},
+	{"hyphen/partial lower", "1.2.0", "1.2 - 2.3", true},
+	{"hyphen/partial upper", "2.3.9", "1.2 - 2.3", true},
+	{"hyphen/partial upper excluded", "2.4.0", "1.2 - 2.3", false},
+	{"hyphen/major upper", "2.9.9", "1.2.3 - 2", true},
+	{"hyphen/major upper excluded", "3.0.0", "1.2.3 - 2", false},
+	{"or", "0.9.0", "<1.0.0 || >=2.0.0 <2.1.0", true},
+	{"or/gap", "1.5.0", "<1.0.0 || >=2.0.0 <2.1.0", false},
+	{"or/second", "2.0.5", "<1.0.0 || >=2.0.0 <2.1.0", true},
+	{"or/second upper", "2.1.0", "<1.0.0 || >=2.0.0 <2.1.0", false},
+	{"or/exact", "1.2.7", "1.2.7 || >=1.2.9 <2.0.0", true},
+	{"or/between", "1.2.8", "1.2.7 || >=1.2.9 <2.0.0", false},
+	{"caret", "1.9.0", "^1.2.3", true},
+	{"caret/zero minor", "0.2.9", "^0.2.3", true},
+	{"caret/zero minor upper", "0.3.0", "^0.2.3", false},
+	{"caret/zero patch", "0.0.4", "^0.0.3", false},
+	{"tilde", "1.2.9", "~1.2", true},
+	{"tilde/upper", "1.3.0", "~1.2", false},
+	{"tilde/ruby style", "1.2.9", "~>1.2.3", true},
+	{"operator spacing", "1.5.0", ">= 1.2.3 < 2", true},
+	{"comma", "1.5.0", ">=1.0.0, <2.0.0", true},
+	{"comma/upper", "2.0.0", ">=1.0.0, <2.0.0", false},
+	{"comma/no space", "1.0.0", ">=1.0.0,<2.0.0", true},
+	{"comma/or", "3.1.0", ">=1.0.0, <2.0.0 || >=3", true},
+	{"prerelease/same tuple", "1.2.3-alpha.7", ">1.2.3-alpha.3", true},
+	{"prerelease/other tuple", "3.4.5-alpha.9", ">1.2.3-alpha.3", false},
+	{"prerelease/release", "3.4.5", ">1.2.3-alpha.3", true},
+	{"prerelease/caret", "1.2.3-beta.4", "^1.2.3-beta.2", true},
+	{"prerelease/caret other tuple", "1.2.4-beta.2", "^1.2.3-beta.2", false},
+	{"prerelease/not in range", "1.1.0-beta", "^1.0.0", false},
+	{"prerelease/any", "1.0.0-beta", "*", false},
+	{"tag/latest", "5.0.0", "latest", true},
+	{"tag/latest prerelease", "5.0.0-beta", "latest", false},
+	{"alias", "4.17.0", "npm:lodash@^4.0.0", true},
+	{"alias/upper", "5.0.0", "npm:lodash@^4.0.0", false},
+}
+
+// npmSpecifierCases はpackage.jsonの依存関係の指定の種類と、レジストリのバージョンに解決できるかどうか
+var npmSpecifierCases = []struct {
+	specifier  string
+	want       NpmSpecifierType
+	resolvable bool
+	aliasName  string
+}{
+	{"^1.2.3", NpmRange, true, ""},
+	{"1.2.3 - 2.3.4", NpmRange, true, ""},
+	{">=1.0.0, <2.0.0", NpmRange, true, ""},
+	{"latest", NpmTag, true, ""},
+	{"next", NpmTag, false, ""},
+	{"npm:lodash@^4.0.0", NpmAlias, true, "lodash"},
+	{"npm:@scope/pkg@1.x", NpmAlias, true, "@scope/pkg"},
+	{"npm:lodash", NpmAlias, true, "lodash"},
+	{"git+https://github.com/user/repo.git", NpmGit, false, ""},
+	{"git://github.com/user/repo.git#v1.0.0", NpmGit, false, ""},
+	{"git@github.com:user/repo.git", NpmGit, false, ""},
+	{"github:user/repo", NpmGit, false, ""},
+	{"user/repo", NpmGit, false, ""},
+	{"user/repo#semver:^1.0.0", NpmGit, false, ""},
+	{"https://github.com/user/repo", NpmGit, false, ""},
+	{"https://example.com/pkg.tgz", NpmTarball, false, ""},
+	{"https://github.com/user/repo/archive/v1.0.0.tar.gz", NpmTarball, false, ""},
+	{"file:../pkg", NpmFile, false, ""},
+	{"./pkg", NpmFile, false, ""},
+	{"../pkg.tgz", NpmFile, false, ""},
+	{"~/pkg", NpmFile, false, ""},
+	{"link:../pkg", NpmFile, false, ""},
+	{"workspace:*", NpmFile, false, ""},
+}
+
+func TestNpmConstraintCheck(t *testing.T) {
+	for _, c := range npmCheckCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			v, err := NewVersion(models.Npm, c.version)
+			if err != nil {
+				t.Fatalf("version %q: %s", c.version, err)
+			}
+			constraint, err := NewNpmConstraint(c.constraint)
+			if err != nil {
+				t.Fatalf("constraint %q: %s", c.constraint, err)
+			}
+			if got := constraint.Check(v); got != c.want {
+				t.Errorf("Check(%q, %q) = %v, want %v", c.version, c.constraint, got, c.want)
+			}
+		})
+	}
+}
+
+func TestNewNpmConstraint(t *testing.T) {
+	for _, c := range npmSpecifierCases {
+		constraint, err := NewNpmConstraint(c.specifier)
+		if err != nil {
+			t.Errorf("parse(%q): %s", c.specifier, err)
+			continue
+		}
+		if constraint.Type != c.want || constraint.Resolvable() != c.resolvable || constraint.AliasName != c.aliasName {
+			t.Errorf("parse(%q) = (%s, %v, %q), want (%s, %v, %q)", c.specifier, constraint.Type, constraint.Resolvable(), constraint.AliasName, c.want, c.resolvable, c.aliasName)
+		}
+	}
+
+	for _, specifier := range []string{"npm:", "npm:lodash@github:user/repo", "latest!?"} {
+		if _, err := NewNpmConstraint(specifier); err == nil {
+			t.Errorf("parse(%q) should fail", specifier)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package sv
+
+import (
+	"analyzer/models"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Range はバージョンの区間の和集合として表したバージョン範囲.
+// 区間は重ならず隣り合わないように昇順に並べて持つので、同じ区間の和集合は同じ文字列になる.
+// 区間はCompareの順序だけで決まり、プレリリースやstabilityのバージョンを除くエコシステムごとの規則は扱わない.
+// 2つの範囲を組み合わせる操作は同じエコシステムの範囲どうしでなければならない
+type Range struct {
+	ecosystem models.EcosystemType
+	intervals []interval
+}
+
+// interval はlowerからupperまでのバージョン. versionがnilの端は限りが無い
+type interval struct {
+	lower bound
+	upper bound
+}
+
+type bound struct {
+	version   Version
+	inclusive bool
+}
+
+// intervalConstraint は区間の和集合に変換できる制約
+type intervalConstraint interface {
+	toIntervals() ([]interval, error)
+}
+
+// NewRange はecosystemの規則で制約を解釈し、区間の和集合にする
+func NewRange(ecosystem models.EcosystemType, constraint string) (*Range, error) {
+	c, err := NewConstraint(ecosystem, constraint)
+	if err != nil {
+		return nil, err
+	}
+	return RangeOf(ecosystem, c)
+}
+
+// RangeOf はNewConstraintで解釈した制約を区間の和集合にする.
+// 解決できない制約(Resolvableがfalse)、Composerのブランチを指す制約、専用の規則が無いエコシステムの制約はエラーにする
+func RangeOf(ecosystem models.EcosystemType, c Constraint) (*Range, error) {
+	ic, ok := c.(intervalConstraint)
+	if !ok {
+		return nil, fmt.Errorf("バージョン範囲として扱えない制約です. constraint: '%s'", c)
+	}
+	intervals, err := ic.toIntervals()
+	if err != nil {
+		return nil, err
+	}
+	return &Range{ecosystem: ecosystem, intervals: normalizeIntervals(intervals)}, nil
+}
+
+// Intersect は両方の範囲に含まれるバージョンの範囲を返す
+func (r *Range) Intersect(o *Range) *Range {
+	return &Range{ecosystem: r.ecosystem, intervals: intersectIntervals(r.intervals, o.intervals)}
+}
+
+// Union はどちらかの範囲に含まれるバージョンの範囲を返す
+func (r *Range) Union(o *Range) *Range {
+	intervals := make([]interval, 0, len(r.intervals)+len(o.intervals))
+	intervals = append(intervals, r.intervals...)
+	intervals = append(intervals, o.intervals...)
+	return &Range{ecosystem: r.ecosystem, intervals: normalizeIntervals(intervals)}
+}
+
+// Subtract はrに含まれoに含まれないバージョンの範囲を返す
+func (r *Range) Subtract(o *Range) *Range {
+	return &Range{ecosystem: r.ecosystem, intervals: intersectIntervals(r.intervals, complementIntervals(o.intervals))}
+}
+
+// IsSubsetOf はrの全てのバージョンがoに含まれるかどうかを返す
+func (r *Range) IsSubsetOf(o *Range) bool {
+	return r.Subtract(o).IsEmpty()
+}
+
+// IsEmpty はどのバージョンも含まない範囲かどうかを返す
+func (r *Range) IsEmpty() bool {
+	return len(r.intervals) == 0
+}
+
+// Check はバージョンが範囲に含まれるかどうかを返す
+func (r *Range) Check(v Version) bool {
+	for _, i := range r.intervals {
+		if i.contains(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Range) Resolvable() bool {
+	return true
+}
+
+// String は脆弱性のバージョン範囲と同じ書き方(>=1.0.0 <1.2.0 || >=2.0.0 <2.0.3)で範囲を返す.
+// 空の範囲を表す書き方はエコシステムに無いので空文字列を返す. 空文字列をNewConstraintで解釈しても空の範囲にはならない
+func (r *Range) String() string {
+	constraints := make([]string, 0, len(r.intervals))
+	for _, i := range r.intervals {
+		constraints = append(constraints, i.format(r.ecosystem))
+	}
+	return strings.Join(constraints, " || ")
+}
+
+func (r *Range) toIntervals() ([]interval, error) {
+	return r.intervals, nil
+}
+
+func (i interval) contains(v Version) bool {
+	if i.lower.version != nil {
+		cmp := v.Compare(i.lower.version)
+		if cmp < 0 || cmp == 0 && !i.lower.inclusive {
+			return false
+		}
+	}
+	if i.upper.version != nil {
+		cmp := v.Compare(i.upper.version)
+		if cmp > 0 || cmp == 0 && !i.upper.inclusive {
+			return false
+		}
+	}
+	return true
+}
+
+func (i interval) isEmpty() bool {
+	if i.lower.version == nil || i.upper.version == nil {
+		return false
+	}
+	cmp := i.lower.version.Compare(i.upper.version)
+	return cmp > 0 || cmp == 0 && !(i.lower.inclusive && i.upper.inclusive)
+}
+
+func (i interval) format(ecosystem models.EcosystemType) string {
+	lower, upper := i.lower, i.upper
+	switch {
+	case lower.version == nil && upper.version == nil:
+		if ecosystem == models.RubyGems {
+			// RubyGemsには * が無いので、Gem::Requirement.defaultと同じ >= 0 にする
+			return ">=0"
+		}
+		return "*"
+	case lower.version != nil && upper.version != nil && lower.inclusive && upper.inclusive && lower.version.Compare(upper.version) == 0:
+		return "=" + lower.version.String()
+	}
+
+	parts := make([]string, 0, 2)
+	if lower.version != nil {
+		op := ">"
+		if lower.inclusive {
+			op = ">="
+		}
+		parts = append(parts, op+lower.version.String())
+	}
+	if upper.version != nil {
+		op := "<"
+		if upper.inclusive {
+			op = "<="
+		}
+		parts = append(parts, op+upper.version.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// compareLower は下端を比べる. 限りの無い下端が最も小さく、同じバージョンなら含むほうが小さい
+func compareLower(a bound, b bound) int {
+	switch {
+	case a.version == nil && b.version == nil:
+		return 0
+	case a.version == nil:
+		return -1
+	case b.version == nil:
+		return 1
+	}
+	if cmp := a.version.Compare(b.version); cmp != 0 {
+		return cmp
+	}
+	return compareInclusive(b.inclusive, a.inclusive)
+}
+
+// compareUpper は上端を比べる. 限りの無い上端が最も大きく、同じバージョンなら含むほうが大きい
+func compareUpper(a bound, b bound) int {
+	switch {
+	case a.version == nil && b.version == nil:
+		return 0
+	case a.version == nil:
+		return 1
+	case b.version == nil:
+		return -1
+	}
+	if cmp := a.version.Compare(b.version); cmp != 0 {
+		return cmp
+	}
+	return compareInclusive(a.inclusive, b.inclusive)
+}
+
+func compareInclusive(a bool, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
+// connected はlowerから始まる区間がupperで終わる区間と重なるか隣り合うかどうかを返す
+func connected(upper bound, lower bound) bool {
+	if upper.version == nil || lower.version == nil {
+		return true
+	}
+	cmp := lower.version.Compare(upper.version)
+	return cmp < 0 || cmp == 0 && (lower.inclusive || upper.inclusive)
+}
+
+// normalizeIntervals は空の区間を除き、重なるか隣り合う区間をつないで昇順に並べる
+func normalizeIntervals(intervals []interval) []interval {
+	sorted := make([]interval, 0, len(intervals))
+	for _, i := range intervals {
+		if !i.isEmpty() {
+			sorted = append(sorted, i)
+		}
+	}
+	sort.SliceStable(sorted, func(a, b int) bool {
+		return compareLower(sorted[a].lower, sorted[b].lower) < 0
+	})
+
+	result := make([]interval, 0, len(sorted))
+	for _, i := range sorted {
+		if len(result) != 0 && connected(result[len(result)-1].upper, i.lower) {
+			last := &result[len(result)-1]
+			if compareUpper(i.upper, last.upper) > 0 {
+				last.upper = i.upper
+			}
+			continue
+		}
+		result = append(result, i)
+	}
+	return result
+}
+
+func intersectIntervals(a []interval, b []interval) []interval {
+	result := make([]interval, 0)
+	for _, x := range a {
+		for _, y := range b {
+			i := x
+			if compareLower(y.lower, i.lower) > 0 {
+				i.lower = y.lower
+			}
+			if compareUpper(y.upper, i.upper) < 0 {
+				i.upper = y.upper
+			}
+			result = append(result, i)
+		}
+	}
+	return normalizeIntervals(result)
+}
+
+// complementIntervals は正規化した区間の和集合に含まれないバージョンの区間を返す
+func complementIntervals(intervals []interval) []interval {
+	result := make([]interval, 0, len(intervals)+1)
+	lower := bound{}
+	for _, i := range intervals {
+		if i.lower.version != nil {
+			result = append(result, interval{lower: lower, upper: bound{version: i.lower.version, inclusive: !i.lower.inclusive}})
+		}
+		if i.upper.version == nil {
+			return result
+		}
+		lower = bound{version: i.upper.version, inclusive: !i.upper.inclusive}
+	}
+	return append(result, interval{lower: lower})
+}
+
+// unionIntervals は制約それぞれの区間の和集合を返す
+func unionIntervals(constraints []Constraint) ([]interval, error) {
+	result := make([]interval, 0)
+	for _, c := range constraints {
+		ic, ok := c.(intervalConstraint)
+		if !ok {
+			return nil, fmt.Errorf("バージョン範囲として扱えない制約です. constraint: '%s'", c)
+		}
+		intervals, err := ic.toIntervals()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, intervals...)
+	}
+	return normalizeIntervals(result), nil
+}
+
+// atLeast, atMost, lessThan, greaterThan, exactly は比較1つの区間
+func atLeast(v Version) []interval {
+	return []interval{{lower: bound{version: v, inclusive: true}}}
+}
+
+func greaterThan(v Version) []interval {
+	return []interval{{lower: bound{version: v}}}
+}
+
+func atMost(v Version) []interval {
+	return []interval{{upper: bound{version: v, inclusive: true}}}
+}
+
+func lessThan(v Version) []interval {
+	return []interval{{upper: bound{version: v}}}
+}
+
+func exactly(v Version) []interval {
+	return []interval{{lower: bound{version: v, inclusive: true}, upper: bound{version: v, inclusive: true}}}
+}
+
+func notEqual(v Version) []interval {
+	return append(lessThan(v), greaterThan(v)...)
+}
+
+// anyVersion は全てのバージョンの区間
+func anyVersion() []interval {
+	return []interval{{}}
+}
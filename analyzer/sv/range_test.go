@@ -0,0 +1,91 @@
+package sv
+
+import (
+	"analyzer/models"
+	"testing"
+)
+
+// railsAdvisoryRange はRubyGemsの脆弱性データでrailsの1つの脆弱性に並んでいた、重なり合う31個の範囲
+const railsAdvisoryRange = ">=2.1.0 <2.3.11 || >=3.0.0 <3.0.4 || >=0 <2.3.11 || >=0 <2.3.11 || >=3.0.0 <3.0.4 || " +
+	">=2.1.0 <2.1.3 || >=2.1.0 <2.1.3 || >=2.2.0 <2.2.2 || >=2.0.0 <2.2.3 || >=2.0.0 <2.2.3 || >=2.3.0 <2.3.4 || " +
+	">=0 <2.2.2 || >=0 <2.2.2 || >=2.3.0 <2.3.5 || >=2.1.0 <2.2.3 || >=2.1.0 <2.2.3 || >=2.3.0 <2.3.4 || >=0 <1.2.5 || " +
+	">=2.3.9 <2.3.10 || >=2.3.9 <2.3.10 || >=3.0.0 <3.0.1 || >=0 <1.2.5 || >=0 <2.0.5 || >=0 <1.2.4 || >=3.0.0 <3.2.17 || " +
+	">=3.0.0 <3.2.17 || >=4.0.0 <4.0.3 || >=0 <1.2.6 || >=2.0.0 <2.3.12 || >=2.0.0 <2.3.12 || >=3.0.0 <3.0.8"
+
+// rangeCases は2つの範囲を組み合わせた結果の書き方. 空の範囲は空文字列になる
+var rangeCases = []struct {
+	name      string
+	ecosystem models.EcosystemType
+	op        string
+	a         string
+	b         string
+	want      string
+}{
+	{"npm/union caret", models.Npm, "union", "^1.0.0", "^2.0.0", ">=1.0.0 <3.0.0"},
+	{"npm/union x-range", models.Npm, "union", "1.x", "2.x", ">=1.0.0 <3.0.0"},
+	{"npm/union tilde", models.Npm, "union", "~1.2.3", "~1.3.0", ">=1.2.3 <1.4.0"},
+	{"npm/union disjoint", models.Npm, "union", ">=2.0.0 <2.0.3", "<1.2.5", "<1.2.5 || >=2.0.0 <2.0.3"},
+	{"npm/union adjacent", models.Npm, "union", ">=1.0.0 <=1.1.0", ">1.1.0 <2.0.0", ">=1.0.0 <2.0.0"},
+	{"npm/union everything", models.Npm, "union", "<1.0.0", ">=1.0.0", "*"},
+	{"npm/union exact", models.Npm, "union", "1.2.3", "=1.2.3", "=1.2.3"},
+	{"npm/subtract", models.Npm, "subtract", "^1.0.0", ">=1.2.0 <1.3.0", ">=1.0.0 <1.2.0 || >=1.3.0 <2.0.0"},
+	{"npm/subtract exact", models.Npm, "subtract", "^1.0.0", "1.5.0", ">=1.0.0 <1.5.0 || >1.5.0 <2.0.0"},
+	{"npm/subtract everything", models.Npm, "subtract", "^1.0.0", "*", ""},
+	{"npm/subtract union", models.Npm, "subtract", "*", "<1.0.0 || >=2.0.0", ">=1.0.0 <2.0.0"},
+	{"npm/intersect", models.Npm, "intersect", "^1.0.0", ">=1.5.0 || <0.5.0", ">=1.5.0 <2.0.0"},
+	{"cargo/union bare", models.Cargo, "union", "0.4", "0.5", ">=0.4.0 <0.6.0"},
+	{"cargo/union zero minor", models.Cargo, "union", "0.0.3", "0.0.4", ">=0.0.3 <0.0.5"},
+	{"cargo/union wildcard", models.Cargo, "union", "1.2.*", "1.3.*", ">=1.2.0 <1.4.0"},
+	{"cargo/union advisory", models.Cargo, "union", ">=0 <0.14.10", ">=0.14.10, <0.15.0", ">=0.0.0 <0.15.0"},
+	{"cargo/subtract exact", models.Cargo, "subtract", "1.2.3", "=1.5.0", ">=1.2.3 <1.5.0 || >1.5.0 <2.0.0"},
+	{"cargo/subtract lower", models.Cargo, "subtract", "^0.4", ">=0.4.3", ">=0.4.0 <0.4.3"},
+	{"rubygems/union rails", models.RubyGems, "union", railsAdvisoryRange, ">=3.0.0 <3.0.11 || >=3.1.0 <3.1.2", ">=0 <2.3.12 || >=3.0.0 <3.2.17 || >=4.0.0 <4.0.3"},
+	{"rubygems/union pessimistic", models.RubyGems, "union", "~> 1.2", "~> 2.0", ">=1.2 <3"},
+	{"rubygems/subtract rails", models.RubyGems, "subtract", railsAdvisoryRange, ">=2.3.0", ">=0 <2.3.0"},
+	{"rubygems/subtract pessimistic", models.RubyGems, "subtract", ">= 0", "~> 1.2.3", ">=0 <1.2.3 || >=1.3"},
+	{"rubygems/subtract everything", models.RubyGems, "subtract", "~> 1.2", ">= 0", ""},
+}
+
+func TestRange(t *testing.T) {
+	for _, c := range rangeCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			a, err := NewRange(c.ecosystem, c.a)
+			if err != nil {
+				t.Fatalf("range %q: %s", c.a, err)
+			}
+			b, err := NewRange(c.ecosystem, c.b)
+			if err != nil {
+				t.Fatalf("range %q: %s", c.b, err)
+			}
+
+			var got *Range
+			switch c.op {
+			case "union":
+				got = a.Union(b)
+			case "subtract":
+				got = a.Subtract(b)
+			default:
+				got = a.Intersect(b)
+			}
+			if got.String() != c.want {
+				t.Errorf("%s(%q, %q) = %q, want %q", c.op, c.a, c.b, got.String(), c.want)
+			}
+			if got.IsEmpty() != (c.want == "") {
+				t.Errorf("%s(%q, %q).IsEmpty() = %v", c.op, c.a, c.b, got.IsEmpty())
+			}
+			if c.want == "" {
+				return
+			}
+
+			// 書き方を解釈し直しても同じ範囲になる
+			reparsed, err := NewRange(c.ecosystem, got.String())
+			if err != nil {
+				t.Fatalf("reparse %q: %s", got.String(), err)
+			}
+			if !reparsed.IsSubsetOf(got) || !got.IsSubsetOf(reparsed) {
+				t.Errorf("reparse %q = %q", got.String(), reparsed.String())
+			}
+		})
+	}
+}
@@ -6,8 +6,8 @@ import (
 	semver "github.com/Masterminds/semver/v3"
 )
 
-func CheckCompliantSemVer(constraint string, okVersion *semver.Version) (models.CompliantType, error) {
-	c, err := semver.NewConstraint(constraint)
+func CheckCompliantSemVer(ecosystem models.EcosystemType, constraint string, okVersion Version) (models.CompliantType, error) {
+	c, err := NewConstraint(ecosystem, constraint)
 	if err != nil {
 		return models.UnKnown, err
 	}
@@ -16,10 +16,14 @@ func CheckCompliantSemVer(constraint string, okVersion *semver.Version) (models.
 		return models.UnKnown, fmt.Errorf("got invalid version: %s with constraint: %s", okVersion.String(), constraint)
 	}
 
+	if ecosystem == models.Cargo {
+		return checkCompliantCargo(c, okVersion), nil
+	}
+
 	if okVersion.Major() == 0 {
 		// 初期開発リリース=単一のバージョン指定
 		// パッチが上がっても制約を満たしていたら、semver非準拠
-		v, err := semver.NewVersion(fmt.Sprintf("%d.%d.%d", okVersion.Major(), okVersion.Minor(), okVersion.Patch()+1))
+		v, err := NewVersion(ecosystem, fmt.Sprintf("%d.%d.%d", okVersion.Major(), okVersion.Minor(), okVersion.Patch()+1))
 		if err != nil {
 			return models.UnKnown, err
 		}
@@ -31,7 +35,7 @@ func CheckCompliantSemVer(constraint string, okVersion *semver.Version) (models.
 	} else {
 		// 本番開発リリース=パッチ&マイナーアップデートは受け入れる
 		// パッチが上がってだめなら、semver非準拠(より厳しい制約)
-		vUpPatch, err := semver.NewVersion(fmt.Sprintf("%d.%d.%d", okVersion.Major(), okVersion.Minor(), okVersion.Patch()+1))
+		vUpPatch, err := NewVersion(ecosystem, fmt.Sprintf("%d.%d.%d", okVersion.Major(), okVersion.Minor(), okVersion.Patch()+1))
 		if err != nil {
 			return models.UnKnown, err
 		}
@@ -40,7 +44,7 @@ func CheckCompliantSemVer(constraint string, okVersion *semver.Version) (models.
 		}
 
 		// マイナーが上がってだめなら、semver非準拠(より厳しい制約)
-		vUpMinor, err := semver.NewVersion(fmt.Sprintf("%d.%d.%d", okVersion.Major(), okVersion.Minor()+1, okVersion.Patch()))
+		vUpMinor, err := NewVersion(ecosystem, fmt.Sprintf("%d.%d.%d", okVersion.Major(), okVersion.Minor()+1, okVersion.Patch()))
 		if err != nil {
 			return models.UnKnown, err
 		}
@@ -49,7 +53,7 @@ func CheckCompliantSemVer(constraint string, okVersion *semver.Version) (models.
 		}
 
 		// メジャーが上がってOKなら、semver非準拠(よりゆるい制約)
-		vUpMajor, err := semver.NewVersion(fmt.Sprintf("%d.%d.%d", okVersion.Major()+1, okVersion.Minor(), okVersion.Patch()))
+		vUpMajor, err := NewVersion(ecosystem, fmt.Sprintf("%d.%d.%d", okVersion.Major()+1, okVersion.Minor(), okVersion.Patch()))
 		if err != nil {
 			return models.UnKnown, err
 		}
@@ -60,3 +64,39 @@ func CheckCompliantSemVer(constraint string, okVersion *semver.Version) (models.
 		return models.Compliant, nil
 	}
 }
+
+// checkCompliantCargo はCargoの互換性の規則で分類する.
+// Cargoは左端の0でない部分を互換性の無い変更とみなすので、0.y.z はyが、0.0.z はzが上がると互換性が無い.
+// その部分より右の更新を受け入れ、その部分の更新を受け入れない制約をsemver準拠とする
+func checkCompliantCargo(c Constraint, okVersion Version) models.CompliantType {
+	vUpPatch := semVersion{semver.New(okVersion.Major(), okVersion.Minor(), okVersion.Patch()+1, "", "")}
+	vUpMinor := semVersion{semver.New(okVersion.Major(), okVersion.Minor()+1, okVersion.Patch(), "", "")}
+	vUpMajor := semVersion{semver.New(okVersion.Major()+1, okVersion.Minor(), okVersion.Patch(), "", "")}
+
+	switch {
+	case okVersion.Major() == 0 && okVersion.Minor() == 0:
+		// パッチが上がってOKなら、semver非準拠(よりゆるい制約)
+		if c.Check(vUpPatch) {
+			return models.ZeroVersionPermissive
+		}
+		return models.ZeroVersionCompliant
+	case okVersion.Major() == 0:
+		// パッチが上がってだめなら、semver非準拠(より厳しい制約)
+		if !c.Check(vUpPatch) {
+			return models.ZeroVersionRestrictive
+		}
+		// マイナーが上がってOKなら、semver非準拠(よりゆるい制約)
+		if c.Check(vUpMinor) {
+			return models.ZeroVersionPermissive
+		}
+		return models.ZeroVersionCompliant
+	default:
+		if !c.Check(vUpPatch) || !c.Check(vUpMinor) {
+			return models.Restrictive
+		}
+		if c.Check(vUpMajor) {
+			return models.Permissive
+		}
+		return models.Compliant
+	}
+}
@@ -0,0 +1,42 @@
+package sv
+
+import (
+	"analyzer/models"
+	semver "github.com/Masterminds/semver/v3"
+)
+
+// Version はエコシステムの規則で解釈したリリースのバージョン
+type Version interface {
+	// Compare はoより古ければ負の値、同じなら0、新しければ正の値を返す. oは同じエコシステムのバージョンでなければならない
+	Compare(o Version) int
+	// Major, Minor, Patch は先頭から3つの数字. 無い部分は0
+	Major() uint64
+	Minor() uint64
+	Patch() uint64
+	String() string
+}
+
+// NewVersion はecosystemの規則でバージョンを解釈する. 専用の規則が無いエコシステムはsemverとして解釈する
+func NewVersion(ecosystem models.EcosystemType, version string) (Version, error) {
+	switch ecosystem {
+	case models.RubyGems:
+		return NewGemVersion(version)
+	case models.Packagist:
+		return NewComposerVersion(version)
+	default:
+		v, err := semver.NewVersion(version)
+		if err != nil {
+			return nil, err
+		}
+		return semVersion{v}, nil
+	}
+}
+
+// semVersion はMastermindsのsemverで解釈したバージョン
+type semVersion struct {
+	*semver.Version
+}
+
+func (v semVersion) Compare(o Version) int {
+	return v.Version.Compare(o.(semVersion).Version)
+}
@@ -2,23 +2,37 @@ package main
 
 import (
 	"analyzer/cmd"
+	"analyzer/config"
 	"analyzer/datasource"
 	"analyzer/models"
-	"database/sql"
+	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
 	"kafka/kafka"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
 func main() {
 	fmt.Println("started...")
 
-	if err := runProducer(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := runProducer(ctx); err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Println("中断しました")
+			stop()
+			os.Exit(1)
+		}
 		panic(err)
 	}
 }
@@ -29,18 +43,33 @@ var roleArnFlag = ""
 var vulPackgeInputFile = ""
 var ecosystemType = ""
 
-func runProducer() error {
+// 1つのメッセージに含める依存元パッケージのリリース履歴の数の目安
+const maxMessageReleaseLogs = 50000
+
+func runProducer(ctx context.Context) error {
 	flag.StringVar(&topicNameFlag, "t", "", "")
 	flag.StringVar(&kafkaEndpointFlag, "k", "", "")
 	flag.StringVar(&roleArnFlag, "r", "", "")
 	flag.StringVar(&vulPackgeInputFile, "f", "", "")
 	flag.StringVar(&ecosystemType, "e", "", "")
+	dbFlags := config.RegisterDatabaseFlags(flag.CommandLine)
 	flag.Parse()
 
-	db, err := sql.Open("mysql", "root@(localhost:3306)/lib")
+	dbConfig, err := dbFlags.Load()
+	if err != nil {
+		return err
+	}
+	// 依存しているパッケージを読みながらprojectsを問い合わせるので、接続が1つだと詰まる
+	if dbConfig.MaxOpenConns == 1 {
+		return fmt.Errorf("max_open_connsは2以上か、無制限なら0を指定してください. max_open_conns: %d", dbConfig.MaxOpenConns)
+	}
+	repository, err := dbConfig.OpenRepository(ctx)
 	if err != nil {
 		return err
 	}
+	if err := repository.CheckSchema(ctx, models.EcosystemType(ecosystemType)); err != nil {
+		return err
+	}
 
 	// 脆弱性のリスト
 	file, err := os.Open(vulPackgeInputFile)
@@ -60,11 +89,23 @@ func runProducer() error {
 		return err
 	}
 
+	// 脆弱性パッケージの名前をまとめてproject_idに解決する
+	packageNames := make([]string, 0, len(rows))
+	for _, row := range rows {
+		packageNames = append(packageNames, row[1])
+	}
+	projectIds, failures, err := datasource.NewPackageResolver(repository).Resolve(ctx, models.EcosystemType(ecosystemType), packageNames)
+	if err != nil {
+		return err
+	}
+	for _, failure := range failures {
+		log.Printf("パッケージ名を解決できませんでした. name: %s, error: %s", failure.Name, failure.Err)
+	}
+
 	vulPackages := make([]cmd.VulPackage, 0)
 	for i := len(rows) - 1; i >= 0; i-- {
-		projectId, err := datasource.GetPackageIdByName(db, models.EcosystemType(ecosystemType), rows[i][1])
-		if err != nil {
-			log.Printf("エラーが発生しました. error: %s", err)
+		projectId, ok := projectIds[rows[i][1]]
+		if !ok {
 			continue
 		}
 		vulPackages = append(vulPackages, cmd.VulPackage{
@@ -72,17 +113,23 @@ func runProducer() error {
 			PackageName:   rows[i][1],
 			VulConstraint: rows[i][2],
 			Deps:          0,
+			Path:          []string{projectId},
+			PublishedAt:   cmd.ParsePublishedAt(rows[i]),
 		})
 	}
 	allVulPackageCount := len(vulPackages)
 
 	for len(vulPackages) != 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		//affectedVulCount := 0
 
 		//vulPakageName := vulPackages[0].PackageName
 		vulPackageId := vulPackages[0].PackageId
 		vulPackageDeps := vulPackages[0].Deps
 		vulConstraint := vulPackages[0].VulConstraint
+		vulPublishedAt := vulPackages[0].PublishedAt
 		vulPackages = vulPackages[1:]
 
 		// 深さ制限
@@ -90,34 +137,70 @@ func runProducer() error {
 			continue
 		}
 
-		// vulPackageに依存しているパッケージを全て取得
-		packages, err := datasource.FetchAffectedPackagesWithVersions(db, models.EcosystemType(ecosystemType), vulPackageId)
-		if err != nil {
-			return err
-		}
-		log.Printf("パッケージ %d/%d, 脆弱性を持ったパッケージ(%s)に依存しているパッケージが %d 個見つかりました", len(vulPackages), allVulPackageCount, vulPackageId, len(packages))
-
 		// 脆弱性パッケージのリリース履歴を取得する
-		vulPackageReleaseLogs, err := datasource.GetVulPackageVersionsById(db, vulPackageId, models.EcosystemType(ecosystemType))
+		vulPackageReleaseLogs, err := repository.GetVulPackageVersionsById(ctx, vulPackageId, models.EcosystemType(ecosystemType))
 		if err != nil {
 			return err
 		}
 
-		// kafkaにメッセージを送る
-		// TODO: メッセージサイズが大きい場合、分割してProduce
-		message, err := json.Marshal(cmd.Message{
-			AffectedPackageReleaseLogs: packages,
-			VulPackageReleaseLogs:      vulPackageReleaseLogs,
-			VulConstraint:              vulConstraint,
+		// kafkaにメッセージを送る. 依存しているパッケージを読みながら、リリース履歴がmaxMessageReleaseLogsを超えるごとに分割して送る
+		packages := make(map[string][]models.ReleaseLog)
+		affectedPackages := make(map[string]*models.Package)
+		releaseLogCount := 0
+		affectedPackageCount := 0
+		produce := func() error {
+			message, err := json.Marshal(cmd.Message{
+				AffectedPackageReleaseLogs: packages,
+				AffectedPackages:           affectedPackages,
+				VulPackageId:               vulPackageId,
+				VulPackageReleaseLogs:      vulPackageReleaseLogs,
+				VulConstraint:              vulConstraint,
+				VulPublishedAt:             vulPublishedAt,
+			})
+			if err != nil {
+				return err
+			}
+			log.Printf("send message to kafka... message size: %d KB", len(message)/1000)
+			if err := kafka.ProduceMessage(message, kafkaEndpointFlag, roleArnFlag, topicNameFlag); err != nil {
+				log.Println("failed to produce message to kafka.", err)
+			}
+			packages = make(map[string][]models.ReleaseLog)
+			affectedPackages = make(map[string]*models.Package)
+			releaseLogCount = 0
+			return nil
+		}
+		err = repository.FetchAffectedPackagesWithVersions(ctx, models.EcosystemType(ecosystemType), vulPackageId, func(affectedPackageId string, releaseLogs []models.ReleaseLog) error {
+			affectedPackageCount++
+			// メッセージごとに送るので、読んでいる途中で別の接続を使ってprojectsを問い合わせる
+			affectedPackage, err := repository.GetPackageById(ctx, affectedPackageId)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Printf("エラーが発生しました. error: %s", err)
+				return nil
+			}
+			packages[affectedPackageId] = releaseLogs
+			affectedPackages[affectedPackageId] = affectedPackage
+			releaseLogCount += len(releaseLogs)
+			if releaseLogCount < maxMessageReleaseLogs {
+				return nil
+			}
+			return produce()
 		})
-		log.Printf("send message to kafka... message size: %d KB", len(message)/1000)
 		if err != nil {
 			return err
 		}
-		if err := kafka.ProduceMessage(message, kafkaEndpointFlag, roleArnFlag, topicNameFlag); err != nil {
-			log.Println("failed to produce message to kafka.", err)
+		if len(packages) != 0 {
+			if err := produce(); err != nil {
+				return err
+			}
+		}
+		log.Printf("パッケージ %d/%d, 脆弱性を持ったパッケージ(%s)に依存しているパッケージが %d 個見つかりました", len(vulPackages), allVulPackageCount, vulPackageId, affectedPackageCount)
+		select {
+		case <-ctx.Done():
+		case <-time.After(3 * time.Second):
 		}
-		time.Sleep(3 * time.Second)
 	}
 	return nil
 }
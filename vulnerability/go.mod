@@ -5,6 +5,12 @@ go 1.19
 require (
 	analyzer v0.0.0
 	github.com/go-sql-driver/mysql v1.7.0
+	github.com/lib/pq v1.10.9
+)
+
+require (
+	github.com/Masterminds/semver/v3 v3.2.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 replace analyzer v0.0.0 => ./../analyzer
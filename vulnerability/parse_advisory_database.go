@@ -1,17 +1,24 @@
 package main
 
 import (
+	"analyzer/config"
 	"analyzer/datasource"
 	"analyzer/models"
-	"database/sql"
+	"analyzer/sv"
+	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 )
 
 func DirWalk(dir string) ([]string, error) {
@@ -44,30 +51,44 @@ const (
 )
 
 var ecosystemMap = map[string]models.EcosystemType{
-	"Packagist":  models.Packagist,
-	"creates.io": models.Cargo,
-	"npm":        models.Npm,
-	"RubyGems":   models.RubyGems,
+	"Packagist": models.Packagist,
+	"crates.io": models.Cargo,
+	"npm":       models.Npm,
+	"RubyGems":  models.RubyGems,
 }
 
-// go run parse_advisory_database.go  packagist packagist_vul_data.csv
+// go run parse_advisory_database.go [-db-dsn ...] packagist packagist_vul_data.csv
 func main() {
-	args := os.Args
-	ecosystem := args[1]
-	outputFilePath := args[2]
+	dbFlags := config.RegisterDatabaseFlags(flag.CommandLine)
+	flag.Parse()
+	ecosystem := flag.Arg(0)
+	outputFilePath := flag.Arg(1)
 
-	if err := handler(ecosystem, outputFilePath); err != nil {
+	dbConfig, err := dbFlags.Load()
+	if err != nil {
+		panic(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := handler(ctx, dbConfig, ecosystem, outputFilePath); err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Println("中断しました")
+			stop()
+			os.Exit(1)
+		}
 		panic(err)
 	}
 }
 
-func handler(ecosystem string, outputFilePath string) error {
+func handler(ctx context.Context, dbConfig config.Database, ecosystem string, outputFilePath string) error {
 	files, err := DirWalk(databaseDir)
 	if err != nil {
 		return err
 	}
 
-	db, err := sql.Open("mysql", "root@(localhost:3306)/lib")
+	repository, err := dbConfig.OpenRepository(ctx)
 	if err != nil {
 		return err
 	}
@@ -76,10 +97,13 @@ func handler(ecosystem string, outputFilePath string) error {
 
 	reports := make([]VulReport, 0)
 	for i, path := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if i%1000 == 0 {
 			log.Printf("走査したファイル %d 件", i)
 		}
-		r, err := ParseCVEFile(db, ecosystem, path)
+		r, err := ParseCVEFile(ecosystem, path)
 		if err != nil {
 			//log.Printf("エラー: %s", err)
 			continue
@@ -87,7 +111,31 @@ func handler(ecosystem string, outputFilePath string) error {
 		reports = append(reports, r...)
 	}
 
+	// パッケージ名をまとめてproject_idに解決し、解決できなかった脆弱性は除く
+	packageNames := make([]string, 0, len(reports))
+	for _, report := range reports {
+		packageNames = append(packageNames, report.PackageName)
+	}
+	projectIds, failures, err := datasource.NewPackageResolver(repository).Resolve(ctx, ecosystemMap[ecosystem], packageNames)
+	if err != nil {
+		return err
+	}
+	for _, failure := range failures {
+		log.Printf("パッケージ名を解決できませんでした. name: %s, error: %s", failure.Name, failure.Err)
+	}
+	resolvedReports := make([]VulReport, 0, len(reports))
+	for _, report := range reports {
+		projectId, ok := projectIds[report.PackageName]
+		if !ok {
+			continue
+		}
+		report.ProjectId = projectId
+		resolvedReports = append(resolvedReports, report)
+	}
+	reports = resolvedReports
+
 	// バリデーション
+	// 同じ脆弱性の同じパッケージはまとめ、バージョン範囲は区間の和集合にして重なりを除く
 	newReports := make([]VulReport, 0)
 	for _, report := range reports {
 		isDuplicate := false
@@ -96,9 +144,8 @@ func handler(ecosystem string, outputFilePath string) error {
 				newReport.PackageName == report.PackageName &&
 				newReport.ProjectId == report.ProjectId {
 				isDuplicate = true
-				if newReport.VersionRange == report.VersionRange {
-				} else {
-					newReports[i].VersionRange = fmt.Sprintf("%s || %s", newReports[i].VersionRange, report.VersionRange)
+				if newReport.VersionRange != report.VersionRange {
+					newReports[i].VersionRange = unionVersionRange(ecosystemMap[ecosystem], newReport.VersionRange, report.VersionRange)
 				}
 			}
 		}
@@ -138,6 +185,27 @@ func handler(ecosystem string, outputFilePath string) error {
 	return nil
 }
 
+// unionVersionRange は2つのバージョン範囲の和集合を、重なりや隣り合う区間をつないだ書き方で返す.
+// 範囲として解釈できない場合や和集合が空の場合は || でつなぐ
+func unionVersionRange(ecosystem models.EcosystemType, a string, b string) string {
+	joined := fmt.Sprintf("%s || %s", a, b)
+	ra, err := sv.NewRange(ecosystem, a)
+	if err != nil {
+		log.Printf("バージョン範囲を解釈できませんでした. range: '%s', error: %s", a, err)
+		return joined
+	}
+	rb, err := sv.NewRange(ecosystem, b)
+	if err != nil {
+		log.Printf("バージョン範囲を解釈できませんでした. range: '%s', error: %s", b, err)
+		return joined
+	}
+	union := ra.Union(rb)
+	if union.IsEmpty() {
+		return joined
+	}
+	return union.String()
+}
+
 type VulReport struct {
 	Summary      string
 	PackageName  string
@@ -172,7 +240,8 @@ type VulRange struct {
 	Fixed      string
 }
 
-func ParseCVEFile(db *sql.DB, ecosystem string, path string) ([]VulReport, error) {
+// ParseCVEFile はadvisoryのファイルからecosystemの脆弱性を読み込む. ProjectIdは呼び出し側でまとめて解決する
+func ParseCVEFile(ecosystem string, path string) ([]VulReport, error) {
 	b, err := GetFileContent(path)
 	if err != nil {
 		return nil, err
@@ -210,17 +279,11 @@ func ParseCVEFile(db *sql.DB, ecosystem string, path string) ([]VulReport, error
 		}
 
 		for _, r := range ranges {
-			projectId, err := datasource.GetPackageIdByName(db, ecosystemMap[ecosystem], af.Package.Name)
-			if err != nil {
-				continue
-			}
-
 			vulReports = append(vulReports, VulReport{
 				Summary:      rawCveReport.Summary,
 				PackageName:  af.Package.Name,
 				VersionRange: fmt.Sprintf("%s %s", r.Introduced, r.Fixed),
 				PublishedAt:  rawCveReport.Published,
-				ProjectId:    projectId,
 			})
 		}
 	}